@@ -0,0 +1,290 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/internal/export"
+)
+
+// exportFormats lists the supported export subcommands
+var exportFormats = map[string]func(args []string) error{
+	"sqlite":   runExportSQLite,
+	"jsonl":    runExportJSONL,
+	"stardict": runExportStarDict,
+	"anki":     runExportAnki,
+	"tei":      runExportTEI,
+	"csv":      runExportCSV,
+}
+
+// runExport dispatches "export <format>" to the exporter for that format
+func runExport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: lexin export <format> [options] (formats: %s)", formatNames())
+	}
+
+	run, ok := exportFormats[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown export format %q (formats: %s)", args[0], formatNames())
+	}
+
+	return run(args[1:])
+}
+
+// formatNames returns the export formats in a stable order
+func formatNames() string {
+	names := ""
+	for _, name := range []string{"sqlite", "jsonl", "stardict", "anki", "tei", "csv"} {
+		if _, ok := exportFormats[name]; ok {
+			if names != "" {
+				names += ", "
+			}
+			names += name
+		}
+	}
+	return names
+}
+
+// scanDictionaries finds the downloaded dictionary files selected by -out and -langs
+func scanDictionaries(outputDir, langs string) ([]catalog.Dictionary, error) {
+	dictionaries, err := catalog.Scan(outputDir, catalog.ParseCodes(langs))
+	if err != nil {
+		return nil, err
+	}
+	if len(dictionaries) == 0 {
+		return nil, fmt.Errorf("no dictionary files found in %s", outputDir)
+	}
+	return dictionaries, nil
+}
+
+// runExportSQLite exports the downloaded dictionaries into a SQLite database
+func runExportSQLite(args []string) error {
+	fs := flag.NewFlagSet("export sqlite", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to export")
+	langs := fs.String("langs", "", "Comma-separated language codes to export (default: all downloaded languages)")
+	dbPath := fs.String("db", "lexin.sqlite", "SQLite database to create (replaced if it exists)")
+	fs.Parse(args)
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	fmt.Printf("Exporting %d dictionaries to %s...\n", len(dictionaries), *dbPath)
+	if err := export.SQLite(*dbPath, dictionaries); err != nil {
+		return err
+	}
+
+	fmt.Printf("Done in %s.\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runExportJSONL writes one JSON Lines file per language pair
+func runExportJSONL(args []string) error {
+	fs := flag.NewFlagSet("export jsonl", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to export")
+	langs := fs.String("langs", "", "Comma-separated language codes to export (default: all downloaded languages)")
+	destDir := fs.String("dest", "lexin_jsonl", "Directory to write the .jsonl files to")
+	compress := fs.Bool("gzip", false, "Compress the output with gzip (.jsonl.gz)")
+	fs.Parse(args)
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*destDir, 0755); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, dict := range dictionaries {
+		name := dict.ID() + ".jsonl"
+		if *compress {
+			name += ".gz"
+		}
+		path := filepath.Join(*destDir, name)
+
+		count, err := export.JSONL(path, dict, *compress)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-12s %6d articles -> %s\n", dict.Code, count, path)
+	}
+
+	fmt.Printf("Done in %s.\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runExportStarDict writes one StarDict bundle per language pair
+func runExportStarDict(args []string) error {
+	fs := flag.NewFlagSet("export stardict", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to export")
+	langs := fs.String("langs", "", "Comma-separated language codes to export (default: all downloaded languages)")
+	destDir := fs.String("dest", "lexin_stardict", "Directory to write the StarDict bundles to (one subdirectory per pair)")
+	fs.Parse(args)
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, dict := range dictionaries {
+		dir := filepath.Join(*destDir, dict.ID())
+		stats, err := export.StarDict(dir, dict)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-12s %6d words, %6d inflected forms -> %s\n", dict.Code, stats.Words, stats.Synonyms, dir)
+	}
+
+	fmt.Printf("Done in %s.\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runExportAnki writes one Anki deck per language pair
+func runExportAnki(args []string) error {
+	fs := flag.NewFlagSet("export anki", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to export")
+	langs := fs.String("langs", "", "Comma-separated language codes to export (default: all downloaded languages)")
+	destDir := fs.String("dest", "lexin_anki", "Directory to write the decks to")
+	format := fs.String("format", "apkg", "Deck format: apkg (Anki package) or tsv (text import)")
+	cards := fs.String("cards", "forward,reverse", "Card templates: forward (Swedish→target), reverse (target→Swedish) or both")
+	wordClasses := fs.String("word-class", "", "Comma-separated word classes to include, e.g. subst,verb (default: all)")
+	maxRank := fs.Int("max-rank", 0, "Only include words with a frequency rank up to this value (default: all)")
+	fs.Parse(args)
+
+	write := export.Anki
+	switch *format {
+	case "apkg":
+	case "tsv":
+		write = export.AnkiTSV
+	default:
+		return fmt.Errorf("unknown Anki format %q (formats: apkg, tsv)", *format)
+	}
+
+	opts := export.AnkiOptions{
+		Cards:       splitList(*cards),
+		WordClasses: splitList(*wordClasses),
+		MaxRank:     *maxRank,
+	}
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*destDir, 0755); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, dict := range dictionaries {
+		path := filepath.Join(*destDir, dict.ID()+"."+*format)
+		count, err := write(path, dict, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-12s %6d notes -> %s\n", dict.Code, count, path)
+	}
+
+	fmt.Printf("Done in %s.\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runExportTEI writes one TEI Lex-0 document per language pair
+func runExportTEI(args []string) error {
+	fs := flag.NewFlagSet("export tei", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to export")
+	langs := fs.String("langs", "", "Comma-separated language codes to export (default: all downloaded languages)")
+	destDir := fs.String("dest", "lexin_tei", "Directory to write the .tei.xml files to")
+	fs.Parse(args)
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*destDir, 0755); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, dict := range dictionaries {
+		path := filepath.Join(*destDir, dict.ID()+".tei.xml")
+		count, err := export.TEI(path, dict)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-12s %6d entries -> %s\n", dict.Code, count, path)
+	}
+
+	fmt.Printf("Done in %s.\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runExportCSV writes one CSV or TSV file per language pair
+func runExportCSV(args []string) error {
+	fs := flag.NewFlagSet("export csv", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to export")
+	langs := fs.String("langs", "", "Comma-separated language codes to export (default: all downloaded languages)")
+	destDir := fs.String("dest", "lexin_csv", "Directory to write the files to")
+	format := fs.String("format", "csv", "File format: csv or tsv")
+	columns := fs.String("columns", strings.Join(export.DefaultCSVColumns, ","),
+		"Comma-separated columns: "+strings.Join(export.CSVColumns, ", "))
+	rows := fs.String("rows", export.CSVRowPerArticle, "One row per article or per translation: article, translation")
+	bom := fs.Bool("bom", false, "Start each file with a UTF-8 byte order mark (helps Excel detect the encoding)")
+	isolate := fs.Bool("isolate-rtl", false, "Wrap right-to-left translations in Unicode isolates")
+	fs.Parse(args)
+
+	opts := export.CSVOptions{
+		Columns:    splitList(*columns),
+		Rows:       *rows,
+		BOM:        *bom,
+		IsolateRTL: *isolate,
+	}
+	switch *format {
+	case "csv":
+		opts.Comma = ','
+	case "tsv":
+		opts.Comma = '\t'
+	default:
+		return fmt.Errorf("unknown format %q (formats: csv, tsv)", *format)
+	}
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*destDir, 0755); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	for _, dict := range dictionaries {
+		path := filepath.Join(*destDir, dict.ID()+"."+*format)
+		count, err := export.CSV(path, dict, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-12s %6d rows -> %s\n", dict.Code, count, path)
+	}
+
+	fmt.Printf("Done in %s.\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// splitList splits a comma-separated option into its trimmed, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
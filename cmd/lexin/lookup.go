@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"getlexin-xml/internal/lookup"
+	"getlexin-xml/pkg/lexin"
+)
+
+// runLookup prints the entries for a word from the downloaded dictionaries
+func runLookup(args []string) error {
+	fs := flag.NewFlagSet("lookup", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to search")
+	langs := fs.String("langs", "", "Comma-separated language codes to search (default: all downloaded languages)")
+	reverse := fs.Bool("reverse", false, "Look up a target-language word and show the Swedish entries it translates")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: lexin lookup [options] <word>")
+		fs.PrintDefaults()
+	}
+
+	// Accept options both before and after the word
+	fs.Parse(args)
+	var words []string
+	for fs.NArg() > 0 {
+		words = append(words, fs.Arg(0))
+		fs.Parse(fs.Args()[1:])
+	}
+	if len(words) == 0 {
+		fs.Usage()
+		return fmt.Errorf("no word given")
+	}
+	word := strings.Join(words, " ")
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+
+	matches, err := lookup.Find(dictionaries, word, *reverse)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("%q not found in %d dictionaries", word, len(dictionaries))
+	}
+
+	language := ""
+	for _, match := range matches {
+		if match.Dictionary.Language != language {
+			language = match.Dictionary.Language
+			fmt.Printf("\n== %s (%s) ==\n", language, match.Dictionary.Code)
+		}
+		if len(match.Terms) > 0 {
+			fmt.Printf("\n%s →", strings.Join(match.Terms, ", "))
+		}
+		printArticle(os.Stdout, match.Article)
+	}
+	return nil
+}
+
+// printArticle writes an article as an indented plain-text entry
+func printArticle(w io.Writer, article *lexin.Article) {
+	for _, lemma := range article.Lemmas {
+		fmt.Fprintf(w, "\n%s", lemma.Value)
+		if p := lemma.Pronunciation(); p != "" {
+			fmt.Fprintf(w, " [%s]", p)
+		}
+		if lemma.WordClass != "" {
+			fmt.Fprintf(w, " (%s)", lemma.WordClass)
+		}
+		fmt.Fprintln(w)
+		if forms := lemma.Forms(); len(forms) > 0 {
+			fmt.Fprintf(w, "  %s\n", strings.Join(forms, ", "))
+		}
+
+		for i, lexeme := range lemma.Lexemes {
+			prefix := "  "
+			if len(lemma.Lexemes) > 1 {
+				prefix = fmt.Sprintf("  %d. ", i+1)
+			}
+			indent := strings.Repeat(" ", len(prefix))
+
+			line := lexin.JoinTranslations(lexeme.Translations)
+			if lexeme.Definition != "" {
+				line = lexeme.Definition + ": " + line
+			}
+			fmt.Fprintf(w, "%s%s\n", prefix, line)
+
+			for _, example := range lexeme.Examples {
+				fmt.Fprintf(w, "%s- %s\n", indent, phrase(example.Value, example.Translations))
+			}
+			for _, idiom := range lexeme.Idioms {
+				fmt.Fprintf(w, "%s* %s\n", indent, phrase(idiom.Value, idiom.Translations))
+			}
+			for _, compound := range lexeme.Compounds {
+				fmt.Fprintf(w, "%s+ %s\n", indent, phrase(compound.Value, compound.Translations))
+			}
+		}
+
+		for _, ref := range lemma.References {
+			fmt.Fprintf(w, "  see also: %s\n", ref.Value)
+		}
+	}
+}
+
+// phrase renders a Swedish phrase with its translations on one line
+func phrase(swedish string, translations []lexin.Translation) string {
+	joined := lexin.JoinTranslations(translations)
+	if joined == "" {
+		return swedish
+	}
+	return swedish + " — " + strings.ReplaceAll(joined, "\n", " ")
+}
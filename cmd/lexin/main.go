@@ -1,39 +1,186 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/dustin/go-humanize"
 
 	"getlexin-xml/internal/fetcher"
+	"getlexin-xml/internal/httpclient"
 	"getlexin-xml/internal/models"
-	"getlexin-xml/internal/parser"
+	"getlexin-xml/internal/ratelimit"
+	"getlexin-xml/internal/retry"
+	"getlexin-xml/internal/source"
 	"getlexin-xml/internal/ui"
 )
 
 func main() {
+	// Subcommands; without one the tool downloads dictionaries
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			if err := runVerify(os.Args[2:]); err != nil {
+				log.Fatalf("Verification failed: %v", err)
+			}
+			return
+		case "export":
+			if err := runExport(os.Args[2:]); err != nil {
+				log.Fatalf("Export failed: %v", err)
+			}
+			return
+		case "lookup":
+			if err := runLookup(os.Args[2:]); err != nil {
+				log.Fatalf("Lookup failed: %v", err)
+			}
+			return
+		case "serve":
+			if err := runServe(os.Args[2:]); err != nil {
+				log.Fatalf("Server failed: %v", err)
+			}
+			return
+		}
+	}
+
 	// Define command line flags
 	outputDir := flag.String("out", "lexin_downloads", "Output directory for downloads")
+	sourceLocation := flag.String("source", source.DefaultLocation, "Where to download from: the URL of an SVN listing, a mirror directory or a .zip/.tar/.tar.gz snapshot")
 	concurrency := flag.Int("concurrency", 3, "Number of concurrent downloads")
+	fileConcurrency := flag.Int("file-concurrency", 1, "Number of files downloaded at the same time within each language")
+	rate := flag.Float64("rate", 0, "Maximum requests per second across all downloads (0 for no limit)")
+	bandwidth := flag.String("bandwidth", "", "Maximum download speed across all downloads, e.g. 500KB or 2MiB per second (empty for no limit)")
+	langs := flag.String("langs", "", "Comma-separated language codes to download without the TUI (e.g. engelska,arabiska)")
+	all := flag.Bool("all", false, "Download all languages without the TUI")
+	noTUI := flag.Bool("no-tui", false, "Never start the interactive TUI (requires -langs or -all)")
+	syncMode := flag.Bool("sync", false, "Only re-download languages whose SVN revision changed since the last run")
+	retries := flag.Int("retries", 4, "Maximum number of attempts per HTTP request")
+	retryBackoff := flag.Duration("retry-backoff", time.Second, "Delay before the first retry, doubled on every further retry")
+	retryMaxBackoff := flag.Duration("retry-max-backoff", 30*time.Second, "Upper bound for a single retry delay, including Retry-After")
+	retryJitter := flag.Float64("retry-jitter", 0.5, "Fraction (0-1) of each retry delay that is randomized")
+	clientConfig := httpclient.DefaultConfig()
+	flag.DurationVar(&clientConfig.ConnectTimeout, "connect-timeout", clientConfig.ConnectTimeout, "Limit for connecting to the server, including the TLS handshake")
+	flag.DurationVar(&clientConfig.ReadTimeout, "read-timeout", clientConfig.ReadTimeout, "Limit for waiting on response headers or the next chunk of data")
+	flag.StringVar(&clientConfig.ProxyURL, "proxy", "", "Proxy URL for all requests (default from HTTP_PROXY/HTTPS_PROXY)")
+	flag.StringVar(&clientConfig.CAFile, "ca-cert", "", "PEM file with root CAs to trust in addition to the system ones")
+	flag.StringVar(&clientConfig.UserAgent, "user-agent", clientConfig.UserAgent, "User-Agent header sent with every request")
+	clientConfig.Headers = make(http.Header)
+	flag.Func("header", "Extra `Name: value` header sent with every request (repeatable)", func(value string) error {
+		name, val, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("expected \"Name: value\", got %q", value)
+		}
+		clientConfig.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(val))
+		return nil
+	})
 	flag.Parse()
 
-	// URL to fetch
-	baseURL := "https://sprakresurser.isof.se/lexin/"
+	// Shared HTTP client for every request
+	client, err := httpclient.New(clientConfig)
+	if err != nil {
+		log.Fatalf("Invalid HTTP client settings: %v", err)
+	}
+
+	// Shared limits for every download
+	var bytesPerSecond uint64
+	if *bandwidth != "" {
+		bytesPerSecond, err = humanize.ParseBytes(*bandwidth)
+		if err != nil {
+			log.Fatalf("Invalid -bandwidth: %v", err)
+		}
+	}
+	if *rate < 0 {
+		log.Fatalf("Invalid -rate: must not be negative")
+	}
+	limiter := ratelimit.New(*rate, int64(bytesPerSecond))
+
+	// Shared retry policy for every HTTP request
+	policy := retry.Policy{
+		MaxAttempts: *retries,
+		BaseDelay:   *retryBackoff,
+		MaxDelay:    *retryMaxBackoff,
+		Jitter:      *retryJitter,
+		Notify: func(attempt int, delay time.Duration, err error) {
+			log.Printf("Attempt %d failed: %v (retrying in %s)", attempt, err, delay.Round(time.Millisecond))
+		},
+	}
+
+	// Any explicit selection implies non-interactive mode
+	nonInteractive := *noTUI || *all || *langs != ""
+	if *noTUI && !*all && *langs == "" {
+		log.Fatalf("-no-tui requires -langs or -all")
+	}
+
+	// Cancel everything on Ctrl+C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Pick the source to download from
+	src, err := source.New(ctx, *sourceLocation, client)
+	if err != nil {
+		log.Fatalf("Invalid source: %v", err)
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if httpSource, ok := src.(*source.HTTP); ok {
+		httpSource.Retry = policy
+		httpSource.Limiter = limiter
+	}
+	opts := downloadOptions{
+		source:          src,
+		outputDir:       *outputDir,
+		concurrency:     *concurrency,
+		fileConcurrency: *fileConcurrency,
+		limiter:         limiter,
+		policy:          policy,
+		sync:            *syncMode,
+	}
 
-	// Fetch the directories from the URL
-	directories, err := parser.FetchDirectories(baseURL)
+	// Fetch the directories from the source
+	directories, err := src.Directories(ctx)
 	if err != nil {
 		log.Fatalf("Failed to fetch directories: %v", err)
 	}
 
-	// Interactive TUI interface
-	p := tea.NewProgram(ui.NewModel(directories, baseURL, *outputDir, *concurrency))
+	// Non-interactive mode: skip the TUI and download the requested languages directly
+	if nonInteractive {
+		selectedDirs, err := selectDirectories(directories, *langs, *all)
+		if err != nil {
+			log.Fatalf("Invalid language selection: %v", err)
+		}
+
+		fmt.Printf("Starting download of %d language directories...\n\n", len(selectedDirs))
+		err = downloadWithProgressReporting(ctx, selectedDirs, opts)
+		if err != nil {
+			log.Fatalf("Error during download: %v", err)
+		}
+
+		fmt.Println("\nAll downloads complete!")
+		return
+	}
+
+	// Interactive TUI interface; retries are not logged since that would garble the screen
+	opts.policy.Notify = nil
+	if httpSource, ok := src.(*source.HTTP); ok {
+		httpSource.Retry = opts.policy
+	}
+	downloadManager, err := newDownloadManager(opts)
+	if err != nil {
+		log.Fatalf("Error during download: %v", err)
+	}
+	// The model watches ctx itself, so that on SIGINT/SIGTERM it can wait for the
+	// cancelled downloads to wind down before quitting
+	p := tea.NewProgram(ui.NewModel(ctx, directories, *sourceLocation, downloadManager), tea.WithoutSignalHandler())
 
 	result, err := p.Run()
 	if err != nil {
@@ -53,16 +200,13 @@ func main() {
 		return
 	}
 
-	// Get selected directories
-	selectedDirs := m.GetSelectedDirectories(directories)
-	if len(selectedDirs) == 0 {
-		fmt.Println("No languages selected. Exiting.")
-		return
+	// The downloads ran inside the TUI; print the final table for the scrollback
+	results := m.Results()
+	printResultHeader()
+	for i, result := range results {
+		fmt.Println(formatResultRow(result, i+1, len(results)))
 	}
-
-	// Start the download process
-	fmt.Printf("\nStarting download of %d language directories...\n\n", len(selectedDirs))
-	err = downloadWithProgressReporting(selectedDirs, *outputDir, *concurrency)
+	err = printSummary(results, len(results), m.Elapsed(), *syncMode)
 	if err != nil {
 		log.Fatalf("Error during download: %v", err)
 	}
@@ -70,61 +214,193 @@ func main() {
 	fmt.Println("\nAll downloads complete!")
 }
 
-// downloadWithProgressReporting handles the downloads and displays progress
-func downloadWithProgressReporting(directories []models.Directory, outputDir string, concurrency int) error {
+// selectDirectories picks the directories named by the -langs and -all flags
+func selectDirectories(directories []models.Directory, langs string, all bool) ([]models.Directory, error) {
+	if all {
+		return directories, nil
+	}
+
+	byCode := make(map[string]models.Directory, len(directories))
+	for _, dir := range directories {
+		byCode[dir.Code] = dir
+	}
+
+	var selected []models.Directory
+	seen := make(map[string]bool)
+	for _, code := range strings.Split(langs, ",") {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		dir, ok := byCode[code]
+		if !ok {
+			available := make([]string, 0, len(directories))
+			for _, d := range directories {
+				available = append(available, d.Code)
+			}
+			return nil, fmt.Errorf("unknown language %q (available: %s)", code, strings.Join(available, ", "))
+		}
+		seen[code] = true
+		selected = append(selected, dir)
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no languages given")
+	}
+
+	return selected, nil
+}
+
+// downloadOptions are the download settings from the command line
+type downloadOptions struct {
+	source          source.Source
+	outputDir       string
+	concurrency     int
+	fileConcurrency int
+	limiter         *ratelimit.Limiter
+	policy          retry.Policy
+	sync            bool
+}
+
+// newDownloadManager creates a download manager configured from the command line
+func newDownloadManager(opts downloadOptions) (*fetcher.DownloadManager, error) {
 	// Create output directory if it doesn't exist
-	err := os.MkdirAll(outputDir, 0755)
+	err := os.MkdirAll(opts.outputDir, 0755)
 	if err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
+		return nil, fmt.Errorf("failed to create output directory: %v", err)
 	}
+
+	downloadManager := fetcher.NewDownloadManager(opts.concurrency, opts.outputDir, opts.source)
+	downloadManager.FileConcurrency = opts.fileConcurrency
+	downloadManager.Limiter = opts.limiter
+	downloadManager.Retry = opts.policy
+	downloadManager.Sync = opts.sync
+	return downloadManager, nil
+}
+
+// downloadWithProgressReporting handles the downloads and displays progress
+func downloadWithProgressReporting(ctx context.Context, directories []models.Directory, opts downloadOptions) error {
 	// Create download manager
-	downloadManager := fetcher.NewDownloadManager(concurrency, outputDir)
+	downloadManager, err := newDownloadManager(opts)
+	if err != nil {
+		return err
+	}
+
+	// Live byte-level progress below the result table
+	progress := newProgressLine()
+	downloadManager.Progress = progress.Update
 
 	// Start downloads in background
-	downloadManager.StartDownloads(directories)
+	downloadManager.StartDownloads(ctx, directories)
 
 	// Track progress
 	startTime := time.Now()
-	completed := 0
 	total := len(directories)
-
-	// Print header
-	fmt.Printf("%-15s %-20s %-10s %-10s\n", "LANGUAGE", "STATUS", "FILES", "SIZE")
-	fmt.Println(strings.Repeat("-", 60))
+	printResultHeader()
 
 	// Process results as they come in
-	var lastError error
+	var results []models.DownloadResult
 	for result := range downloadManager.Results {
-		completed++
-		status := "ERROR"
-		if result.Success {
-			status = "COMPLETED"
-		} else if result.Error != nil {
-			lastError = result.Error
-		}
+		results = append(results, result)
+		progress.Printf("%s\n", formatResultRow(result, len(results), total))
+	}
 
-		sizeStr := "N/A"
-		if result.Success {
-			sizeStr = formatBytes(result.TotalBytes)
+	progress.Finish()
+
+	return printSummary(results, total, time.Since(startTime), opts.sync)
+}
+
+// printResultHeader prints the header of the result table
+func printResultHeader() {
+	fmt.Printf("%-15s %-20s %-10s %-10s %-10s\n", "LANGUAGE", "STATUS", "FETCHED", "UNCHANGED", "SIZE")
+	fmt.Println(strings.Repeat("-", 70))
+}
+
+// formatResultRow renders one language result as a row of the result table
+func formatResultRow(result models.DownloadResult, completed, total int) string {
+	status := "ERROR"
+	if result.UpToDate {
+		status = "UP-TO-DATE"
+	} else if result.Success {
+		status = "COMPLETED"
+	} else if result.Partial {
+		status = "PARTIAL"
+	}
+
+	sizeStr := "N/A"
+	if (result.Success || result.Partial) && !result.UpToDate {
+		sizeStr = formatBytes(result.TotalBytes)
+	}
+
+	return fmt.Sprintf("%-15s %-20s %-10d %-10d %-10s [%d/%d]",
+		result.Directory.Code,
+		status,
+		result.FileCount,
+		result.NotModifiedCount,
+		sizeStr,
+		completed,
+		total)
+}
+
+// printSummary lists failed files and totals, returning an error if any language failed
+func printSummary(results []models.DownloadResult, total int, elapsed time.Duration, syncMode bool) error {
+	failed := 0
+	upToDate := 0
+	fetchedFiles := 0
+	unchangedFiles := 0
+	var lastError error
+	var incomplete []models.DownloadResult
+	for _, result := range results {
+		fetchedFiles += result.FileCount
+		unchangedFiles += result.NotModifiedCount
+		if result.UpToDate {
+			upToDate++
+		}
+		if !result.Success {
+			failed++
+			if result.Partial {
+				incomplete = append(incomplete, result)
+			}
+			if result.Error != nil {
+				lastError = result.Error
+			}
 		}
+	}
 
-		// Print the result
-		fmt.Printf("%-15s %-20s %-10d %-10s [%d/%d]\n",
-			result.Directory.Code,
-			status,
-			result.FileCount,
-			sizeStr,
-			completed,
-			total)
+	// List the files missing from partially downloaded languages
+	if len(incomplete) > 0 {
+		fmt.Printf("\nFailed files:\n")
+		for _, result := range incomplete {
+			for _, file := range result.FailedFiles() {
+				status := "-"
+				if file.HTTPStatus != 0 {
+					status = fmt.Sprintf("%d", file.HTTPStatus)
+				}
+				fmt.Printf("- %s/%s (HTTP %s): %v\n", result.Directory.Code, file.Name, status, file.Error)
+			}
+		}
 	}
 
 	// Print summary
-	elapsed := time.Since(startTime)
+	completed := len(results)
 	fmt.Printf("\nDownload summary:\n")
 	fmt.Printf("- Languages processed: %d\n", completed)
+	if syncMode {
+		fmt.Printf("- Languages up to date: %d\n", upToDate)
+	}
+	fmt.Printf("- Languages failed: %d\n", failed)
+	fmt.Printf("- Files fetched: %d\n", fetchedFiles)
+	fmt.Printf("- Files unchanged: %d\n", unchangedFiles)
 	fmt.Printf("- Time elapsed: %s\n", elapsed.Round(time.Second))
 
-	return lastError
+	if failed > 0 {
+		return fmt.Errorf("%d of %d languages failed, last error: %v", failed, total, lastError)
+	}
+	if completed < total {
+		return fmt.Errorf("only %d of %d languages were processed", completed, total)
+	}
+
+	return nil
 }
 
 // formatBytes converts bytes to human readable string using go-humanize
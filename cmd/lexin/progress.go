@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+
+	"getlexin-xml/internal/models"
+)
+
+// progressRefresh limits how often the progress line is redrawn
+const progressRefresh = 200 * time.Millisecond
+
+// progressLine renders the live progress of every active file on a single terminal line.
+// It is a no-op when stdout is not a terminal, so logs from cron jobs stay clean.
+type progressLine struct {
+	mu       sync.Mutex
+	enabled  bool
+	active   map[string]models.ProgressEvent // Latest event by "<language>/<file>"
+	lastDraw time.Time
+	drawn    bool
+}
+
+// newProgressLine creates a progress line for stdout
+func newProgressLine() *progressLine {
+	return &progressLine{
+		enabled: term.IsTerminal(os.Stdout.Fd()),
+		active:  make(map[string]models.ProgressEvent),
+	}
+}
+
+// Update records a progress event and redraws the line if enough time has passed
+func (p *progressLine) Update(event models.ProgressEvent) {
+	if !p.enabled {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := event.Directory + "/" + event.File
+	if event.Done {
+		delete(p.active, key)
+	} else {
+		p.active[key] = event
+	}
+
+	if time.Since(p.lastDraw) >= progressRefresh {
+		p.draw()
+	}
+}
+
+// Printf prints a message above the progress line
+func (p *progressLine) Printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	fmt.Printf(format, args...)
+	if p.enabled {
+		p.draw()
+	}
+}
+
+// Finish removes the progress line
+func (p *progressLine) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	p.active = make(map[string]models.ProgressEvent)
+}
+
+// clear erases the progress line; the caller must hold the lock
+func (p *progressLine) clear() {
+	if p.drawn {
+		fmt.Print("\r\033[K")
+		p.drawn = false
+	}
+}
+
+// draw renders the progress line; the caller must hold the lock
+func (p *progressLine) draw() {
+	p.lastDraw = time.Now()
+	p.clear()
+	if len(p.active) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(p.active))
+	for key := range p.active {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, formatProgress(p.active[key]))
+	}
+	line := strings.Join(parts, " | ")
+
+	// Keep the line from wrapping, which would break the carriage return redraw
+	if width, _, err := term.GetSize(os.Stdout.Fd()); err == nil && width > 1 {
+		runes := []rune(line)
+		if len(runes) > width-1 {
+			line = string(runes[:width-1])
+		}
+	}
+
+	fmt.Print(line)
+	p.drawn = true
+}
+
+// formatProgress renders a compact summary of one event, e.g. "engelska 2/3 [#####-----] 50% 1.2 MB/s"
+func formatProgress(event models.ProgressEvent) string {
+	const barWidth = 10
+
+	progress := fmt.Sprintf("%s %d/%d", event.Directory, event.FileIndex, event.FileCount)
+	if event.ContentLength > 0 {
+		ratio := float64(event.BytesRead) / float64(event.ContentLength)
+		ratio = min(max(ratio, 0), 1)
+		filled := int(ratio * barWidth)
+		progress += fmt.Sprintf(" [%s%s] %3.0f%%", strings.Repeat("#", filled), strings.Repeat("-", barWidth-filled), ratio*100)
+	} else {
+		progress += " " + formatBytes(event.BytesRead)
+	}
+	if event.Throughput > 0 {
+		progress += fmt.Sprintf(" %s/s", formatBytes(int64(event.Throughput)))
+	}
+
+	return progress
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"getlexin-xml/internal/server"
+)
+
+// runServe serves the downloaded dictionaries over HTTP until interrupted
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to serve")
+	langs := fs.String("langs", "", "Comma-separated language codes to serve (default: all downloaded languages)")
+	addr := fs.String("addr", "127.0.0.1:8080", "Address to listen on")
+	cors := fs.String("cors", "", "Comma-separated origins allowed to call the API from a browser, or * for any")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 10*time.Second, "Time to let open requests finish on shutdown")
+	fs.Parse(args)
+
+	dictionaries, err := scanDictionaries(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	fmt.Printf("Loading %d dictionaries...\n", len(dictionaries))
+	api, err := server.New(dictionaries, server.Options{AllowedOrigins: splitList(*cors)})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded in %s.\n", time.Since(start).Round(time.Millisecond))
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           api,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	fmt.Printf("Serving on http://%s\n", *addr)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
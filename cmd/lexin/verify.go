@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/internal/manifest"
+)
+
+// runVerify re-hashes the downloaded files and compares them with the language manifests
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	outputDir := fs.String("out", "lexin_downloads", "Download directory to verify")
+	langs := fs.String("langs", "", "Comma-separated language codes to verify (default: all downloaded languages)")
+	fs.Parse(args)
+
+	codes, err := downloadedLanguages(*outputDir, *langs)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return fmt.Errorf("no downloaded languages found in %s", *outputDir)
+	}
+
+	fmt.Printf("%-15s %-10s %s\n", "LANGUAGE", "STATUS", "DETAILS")
+	fmt.Println(strings.Repeat("-", 70))
+
+	failed := 0
+	for _, code := range codes {
+		problems, err := manifest.Verify(filepath.Join(*outputDir, code))
+		if errors.Is(err, os.ErrNotExist) {
+			failed++
+			fmt.Printf("%-15s %-10s %s\n", code, "ERROR", "no manifest, download the language again")
+			continue
+		}
+		if err != nil {
+			failed++
+			fmt.Printf("%-15s %-10s %v\n", code, "ERROR", err)
+			continue
+		}
+
+		if len(problems) == 0 {
+			fmt.Printf("%-15s %-10s\n", code, "OK")
+			continue
+		}
+
+		failed++
+		fmt.Printf("%-15s %-10s %d problems\n", code, "FAILED", len(problems))
+		for _, problem := range problems {
+			line := fmt.Sprintf("  - %s: %s", problem.Kind, problem.File)
+			if problem.Detail != "" {
+				line += " (" + problem.Detail + ")"
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d languages failed verification", failed, len(codes))
+	}
+
+	fmt.Printf("\nAll %d languages verified.\n", len(codes))
+	return nil
+}
+
+// downloadedLanguages returns the requested language codes, or every language directory in outputDir
+func downloadedLanguages(outputDir, langs string) ([]string, error) {
+	if langs != "" {
+		return catalog.ParseCodes(langs), nil
+	}
+
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download directory: %v", err)
+	}
+
+	var codes []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			codes = append(codes, entry.Name())
+		}
+	}
+	sort.Strings(codes)
+
+	return codes, nil
+}
@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"getlexin-xml/internal/manifest"
+	"getlexin-xml/internal/models"
+)
+
+// Dictionary is a downloaded Lexin XML file for one language pair
+type Dictionary struct {
+	Code     string // Language directory code (e.g. "engelska")
+	Language string // Human-readable language name (e.g. "English")
+	File     string // File name (e.g. "swe_eng.xml")
+	Path     string // Full path on disk
+	Revision string // SVN revision from the manifest, if known
+}
+
+// ID identifies the language pair by its file name without extension (e.g. "swe_eng")
+func (d Dictionary) ID() string {
+	return strings.TrimSuffix(d.File, filepath.Ext(d.File))
+}
+
+// LanguageTag returns the BCP 47 tag of the target language, or "und" if it is unknown
+func (d Dictionary) LanguageTag() string {
+	if tag, ok := models.LanguageTags[d.Code]; ok {
+		return tag
+	}
+	return "und"
+}
+
+// RightToLeft reports whether the target language is written right to left
+func (d Dictionary) RightToLeft() bool {
+	return models.RightToLeft[d.Code]
+}
+
+// Scan finds the dictionary files in a download directory.
+// If codes is non-empty only those language directories are included.
+func Scan(outputDir string, codes []string) ([]Dictionary, error) {
+	if len(codes) == 0 {
+		entries, err := os.ReadDir(outputDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read download directory: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				codes = append(codes, entry.Name())
+			}
+		}
+	}
+	sort.Strings(codes)
+
+	var dictionaries []Dictionary
+	for _, code := range codes {
+		dirPath := filepath.Join(outputDir, code)
+		matches, err := filepath.Glob(filepath.Join(dirPath, "*.xml"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			if _, err := os.Stat(dirPath); err != nil {
+				return nil, fmt.Errorf("language %q is not downloaded in %s", code, outputDir)
+			}
+			continue
+		}
+		sort.Strings(matches)
+
+		// The revision is best effort; older downloads have no manifest
+		revision := ""
+		if m, err := manifest.Load(dirPath); err == nil {
+			revision = m.Revision
+		}
+
+		language, ok := models.LanguageMap[code]
+		if !ok {
+			language = code
+		}
+
+		for _, path := range matches {
+			dictionaries = append(dictionaries, Dictionary{
+				Code:     code,
+				Language: language,
+				File:     filepath.Base(path),
+				Path:     path,
+				Revision: revision,
+			})
+		}
+	}
+
+	return dictionaries, nil
+}
+
+// ParseCodes splits a comma-separated list of language codes
+func ParseCodes(langs string) []string {
+	var codes []string
+	for _, code := range strings.Split(langs, ",") {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,480 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+// Card templates of an Anki export
+const (
+	AnkiForward = "forward" // Swedish on the front, translation on the back
+	AnkiReverse = "reverse" // Translation on the front, Swedish on the back
+)
+
+// AnkiOptions selects the cards and the words of an Anki export
+type AnkiOptions struct {
+	Cards       []string // AnkiForward and/or AnkiReverse; both if empty
+	WordClasses []string // Only include these word classes, e.g. "subst.", "verb"; all if empty
+	MaxRank     int      // Only include lemmas with a frequency rank up to this value; all if zero
+}
+
+// ankiFields are the fields of the note type, in order
+var ankiFields = []string{"Swedish", "WordClass", "Pronunciation", "Inflections", "Translation", "Definition", "Examples"}
+
+// ankiNote is one lemma as an Anki note
+type ankiNote struct {
+	guid   string
+	fields []string
+	tags   []string
+}
+
+// ankiTemplate is a card template of the note type
+type ankiTemplate struct {
+	name     string
+	front    string
+	back     string
+	required int // Index of the field the card cannot be empty without
+}
+
+// ankiTemplates returns the card templates for the selected directions
+func ankiTemplates(dict catalog.Dictionary, cards []string) ([]ankiTemplate, error) {
+	if len(cards) == 0 {
+		cards = []string{AnkiForward, AnkiReverse}
+	}
+
+	var templates []ankiTemplate
+	for _, card := range cards {
+		switch card {
+		case AnkiForward:
+			templates = append(templates, ankiTemplate{
+				name:     "Svenska → " + dict.Language,
+				front:    `<div class="sv">{{Swedish}}</div><div class="meta">{{WordClass}}</div>`,
+				back:     `{{FrontSide}}<hr id="answer"><div class="tr">{{Translation}}</div>{{#Definition}}<div class="def">{{Definition}}</div>{{/Definition}}{{#Examples}}<div class="ex">{{Examples}}</div>{{/Examples}}`,
+				required: 0,
+			})
+		case AnkiReverse:
+			templates = append(templates, ankiTemplate{
+				name:     dict.Language + " → svenska",
+				front:    `<div class="tr">{{Translation}}</div>`,
+				back:     `{{FrontSide}}<hr id="answer"><div class="sv">{{Swedish}}</div><div class="meta">{{WordClass}} {{Pronunciation}}</div>{{#Inflections}}<div class="meta">{{Inflections}}</div>{{/Inflections}}{{#Examples}}<div class="ex">{{Examples}}</div>{{/Examples}}`,
+				required: 4,
+			})
+		default:
+			return nil, fmt.Errorf("unknown card template %q (templates: %s, %s)", card, AnkiForward, AnkiReverse)
+		}
+	}
+	return templates, nil
+}
+
+// ankiCSS styles the cards; translations follow the script direction of the language
+func ankiCSS(dict catalog.Dictionary) string {
+	direction := "ltr"
+	if dict.RightToLeft() {
+		direction = "rtl"
+	}
+	return ".card { font-family: sans-serif; font-size: 22px; text-align: center; }\n" +
+		".sv { font-size: 30px; font-weight: bold; }\n" +
+		".meta { color: #777; font-size: 16px; }\n" +
+		".tr { direction: " + direction + "; unicode-bidi: isolate; }\n" +
+		".def { font-style: italic; margin-top: 8px; }\n" +
+		".ex { font-size: 16px; margin-top: 12px; text-align: left; }\n"
+}
+
+// ankiNotes reads the lemmas of a dictionary that pass the filters as notes
+func ankiNotes(dict catalog.Dictionary, opts AnkiOptions) ([]ankiNote, error) {
+	wordClasses := make(map[string]bool)
+	for _, class := range opts.WordClasses {
+		wordClasses[normalizeWordClass(class)] = true
+	}
+
+	var notes []ankiNote
+	for article, err := range lexin.Articles(dict.Path) {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", dict.File, err)
+		}
+
+		for _, lemma := range article.Lemmas {
+			if len(wordClasses) > 0 && !wordClasses[normalizeWordClass(lemma.WordClass)] {
+				continue
+			}
+			if opts.MaxRank > 0 {
+				if rank, ok := lemma.RankValue(); !ok || rank > opts.MaxRank {
+					continue
+				}
+			}
+			if note, ok := newAnkiNote(dict, article, &lemma); ok {
+				notes = append(notes, note)
+			}
+		}
+	}
+	return notes, nil
+}
+
+// newAnkiNote renders a lemma into note fields; lemmas without translations are skipped
+func newAnkiNote(dict catalog.Dictionary, article *lexin.Article, lemma *lexin.Lemma) (ankiNote, bool) {
+	esc := html.EscapeString
+
+	var translations, definitions, examples []string
+	numbered := len(lemma.Lexemes) > 1
+	for i, lexeme := range lemma.Lexemes {
+		joined := lexin.JoinTranslations(lexeme.Translations)
+		if joined == "" {
+			continue
+		}
+		translation := esc(joined)
+		definition := esc(lexeme.Definition)
+		if numbered {
+			translation = fmt.Sprintf("%d. %s", i+1, translation)
+			if definition != "" {
+				definition = fmt.Sprintf("%d. %s", i+1, definition)
+			}
+		}
+		translations = append(translations, translation)
+		if definition != "" {
+			definitions = append(definitions, definition)
+		}
+		for _, example := range lexeme.Examples {
+			examples = append(examples, fmt.Sprintf("<i>%s</i> – %s", esc(example.Value), esc(lexin.JoinTranslations(example.Translations))))
+		}
+		for _, idiom := range lexeme.Idioms {
+			examples = append(examples, fmt.Sprintf("<i>%s</i> – %s", esc(idiom.Value), esc(lexin.JoinTranslations(idiom.Translations))))
+		}
+	}
+	if len(translations) == 0 {
+		return ankiNote{}, false
+	}
+
+	tags := []string{"lexin", dict.ID()}
+	if lemma.WordClass != "" {
+		tags = append(tags, strings.Join(strings.Fields(normalizeWordClass(lemma.WordClass)), "_"))
+	}
+
+	return ankiNote{
+		guid: dict.ID() + ":" + article.ID + ":" + lemma.ID,
+		fields: []string{
+			esc(lemma.Value),
+			esc(lemma.WordClass),
+			esc(lemma.Pronunciation()),
+			esc(strings.Join(lemma.Forms(), ", ")),
+			strings.Join(translations, "<br>"),
+			strings.Join(definitions, "<br>"),
+			strings.Join(examples, "<br>"),
+		},
+		tags: tags,
+	}, true
+}
+
+// normalizeWordClass makes "Subst" and "subst." match
+func normalizeWordClass(class string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(class)), ".")
+}
+
+// AnkiTSV writes the notes of a dictionary as a tab-separated file that Anki
+// imports into its built-in "Basic" note types. It returns the number of notes.
+func AnkiTSV(path string, dict catalog.Dictionary, opts AnkiOptions) (int, error) {
+	templates, err := ankiTemplates(dict, opts.Cards)
+	if err != nil {
+		return 0, err
+	}
+	notes, err := ankiNotes(dict, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	// The built-in note types only have Front and Back, so the reverse-only
+	// deck swaps the sides and the two-way deck uses the reversed note type
+	notetype := "Basic"
+	if len(templates) > 1 {
+		notetype = "Basic (and reversed card)"
+	}
+	reverseOnly := len(templates) == 1 && templates[0].required != 0
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "#separator:tab\n#html:true\n#notetype:%s\n#deck:%s\n#guid column:1\n#tags column:4\n", notetype, ankiDeckName(dict))
+
+	w := csv.NewWriter(f)
+	w.Comma = '\t'
+	for _, note := range notes {
+		front := fmt.Sprintf("%s <small>%s</small>", note.fields[0], note.fields[1])
+		back := note.fields[4]
+		if dict.RightToLeft() {
+			back = `<div dir="rtl">` + back + `</div>`
+		}
+		if note.fields[5] != "" {
+			back += "<br><i>" + note.fields[5] + "</i>"
+		}
+		if note.fields[6] != "" {
+			back += "<br><br>" + note.fields[6]
+		}
+		if reverseOnly {
+			front, back = back, front
+		}
+		if err := w.Write([]string{note.guid, front, back, strings.Join(note.tags, " ")}); err != nil {
+			return 0, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, err
+	}
+
+	return len(notes), f.Close()
+}
+
+// ankiDeckName is the deck the notes of a dictionary are filed under
+func ankiDeckName(dict catalog.Dictionary) string {
+	return "Lexin::svenska-" + dict.Language
+}
+
+// ankiSchema is the collection schema (version 11) that .apkg files carry
+const ankiSchema = `
+CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null);
+CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null);
+CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null);
+CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);
+`
+
+// Anki writes the notes of a dictionary as an Anki package (.apkg) with its own
+// note type and deck. It returns the number of notes.
+func Anki(path string, dict catalog.Dictionary, opts AnkiOptions) (int, error) {
+	templates, err := ankiTemplates(dict, opts.Cards)
+	if err != nil {
+		return 0, err
+	}
+	notes, err := ankiNotes(dict, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "lexin-anki-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	collection := filepath.Join(tmpDir, "collection.anki2")
+	if err := writeAnkiCollection(collection, dict, templates, notes); err != nil {
+		return 0, err
+	}
+
+	if err := writeAnkiPackage(path, collection); err != nil {
+		return 0, err
+	}
+	return len(notes), nil
+}
+
+// writeAnkiCollection creates the collection database inside the package
+func writeAnkiCollection(path string, dict catalog.Dictionary, templates []ankiTemplate, notes []ankiNote) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(ankiSchema); err != nil {
+		return fmt.Errorf("failed to create Anki collection: %v", err)
+	}
+
+	now := time.Now()
+	deckName := ankiDeckName(dict)
+	deckID := ankiID(deckName)
+	var templateNames []string
+	for _, t := range templates {
+		templateNames = append(templateNames, t.name)
+	}
+	modelID := ankiID("Lexin " + dict.ID() + " " + strings.Join(templateNames, "|"))
+
+	models, decks, dconf, conf, err := ankiCollectionConfig(dict, templates, modelID, deckID, deckName, now, len(notes))
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		now.Unix(), now.UnixMilli(), now.UnixMilli(), conf, models, decks, dconf); err != nil {
+		return err
+	}
+
+	noteStmt, err := tx.Prepare(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`)
+	if err != nil {
+		return err
+	}
+	cardStmt, err := tx.Prepare(`INSERT INTO cards VALUES (?, ?, ?, ?, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')`)
+	if err != nil {
+		return err
+	}
+
+	// Note and card ids are millisecond timestamps in Anki; count up from now
+	nextID := now.UnixMilli()
+	for i, note := range notes {
+		noteID := nextID
+		nextID++
+		tags := " " + strings.Join(note.tags, " ") + " "
+		if _, err := noteStmt.Exec(noteID, note.guid, modelID, now.Unix(), tags,
+			strings.Join(note.fields, "\x1f"), note.fields[0], ankiChecksum(note.fields[0])); err != nil {
+			return err
+		}
+
+		for ord, template := range templates {
+			if note.fields[template.required] == "" {
+				continue
+			}
+			if _, err := cardStmt.Exec(nextID, noteID, deckID, ord, now.Unix(), i+1); err != nil {
+				return err
+			}
+			nextID++
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ankiCollectionConfig builds the JSON blobs of the col table
+func ankiCollectionConfig(dict catalog.Dictionary, templates []ankiTemplate, modelID, deckID int64, deckName string, now time.Time, noteCount int) (models, decks, dconf, conf string, err error) {
+	var fields []map[string]any
+	for i, name := range ankiFields {
+		fields = append(fields, map[string]any{
+			"name": name, "ord": i, "sticky": false, "rtl": name == "Translation" && dict.RightToLeft(),
+			"font": "Arial", "size": 20, "media": []any{},
+		})
+	}
+	var tmpls []map[string]any
+	var req []any
+	for i, t := range templates {
+		tmpls = append(tmpls, map[string]any{
+			"name": t.name, "ord": i, "qfmt": t.front, "afmt": t.back,
+			"did": nil, "bqfmt": "", "bafmt": "",
+		})
+		req = append(req, []any{i, "any", []int{t.required}})
+	}
+
+	model := map[string]any{
+		"id": modelID, "name": "Lexin " + dict.ID(), "type": 0, "mod": now.Unix(), "usn": -1,
+		"sortf": 0, "did": deckID, "tmpls": tmpls, "flds": fields, "css": ankiCSS(dict),
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}", "tags": []any{}, "vers": []any{}, "req": req,
+	}
+	deck := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": "", "mod": now.Unix(), "usn": -1,
+			"lrnToday": []int{0, 0}, "revToday": []int{0, 0}, "newToday": []int{0, 0}, "timeToday": []int{0, 0},
+			"collapsed": false, "browserCollapsed": false, "dyn": 0, "conf": 1, "extendNew": 0, "extendRev": 0,
+		}
+	}
+	options := map[string]any{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0,
+		"replayq": true, "dyn": false,
+		"new": map[string]any{"bury": false, "delays": []int{1, 10}, "initialFactor": 2500,
+			"ints": []int{1, 4, 0}, "order": 1, "perDay": 20},
+		"rev":   map[string]any{"bury": false, "ease4": 1.3, "ivlFct": 1, "maxIvl": 36500, "perDay": 200, "hardFactor": 1.2},
+		"lapse": map[string]any{"delays": []int{10}, "leechAction": 1, "leechFails": 8, "minInt": 1, "mult": 0},
+	}
+	config := map[string]any{
+		"activeDecks": []int64{deckID}, "curDeck": deckID, "newSpread": 0, "collapseTime": 1200,
+		"timeLim": 0, "estTimes": true, "dueCounts": true, "curModel": modelID, "nextPos": noteCount + 1,
+		"sortType": "noteFld", "sortBackwards": false, "addToCur": true,
+	}
+
+	blobs := []any{
+		map[string]any{strconv.FormatInt(modelID, 10): model},
+		map[string]any{"1": deck(1, "Default"), strconv.FormatInt(deckID, 10): deck(deckID, deckName)},
+		map[string]any{"1": options},
+		config,
+	}
+	var out [4]string
+	for i, blob := range blobs {
+		data, err := json.Marshal(blob)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		out[i] = string(data)
+	}
+	return out[0], out[1], out[2], out[3], nil
+}
+
+// writeAnkiPackage zips the collection into an .apkg with an empty media map
+func writeAnkiPackage(path, collection string) error {
+	data, err := os.ReadFile(collection)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"collection.anki2", data},
+		{"media", []byte("{}")},
+	} {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// ankiID derives a stable id from a name, so re-importing a deck updates it
+// instead of creating a copy
+func ankiID(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64()%(1<<40)) + 1<<40
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// ankiChecksum is the duplicate-detection checksum Anki stores for the sort field
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(html.UnescapeString(htmlTag.ReplaceAllString(field, ""))))
+	value, _ := strconv.ParseInt(hex.EncodeToString(sum[:4]), 16, 64)
+	return value
+}
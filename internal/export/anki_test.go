@@ -0,0 +1,181 @@
+package export
+
+import (
+	"archive/zip"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ankiCard is a card of an exported collection with the note it belongs to
+type ankiCard struct {
+	guid     string
+	swedish  string
+	ord      int
+	deckID   int64
+	modelID  int64
+	noteTags string
+}
+
+// readAnkiPackage opens the collection inside an .apkg and returns its cards in note order
+func readAnkiPackage(t *testing.T, path string) []ankiCard {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("opening package: %v", err)
+	}
+	defer zr.Close()
+
+	collection := filepath.Join(t.TempDir(), "collection.anki2")
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name != "collection.anki2" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(collection, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(names, ",") != "collection.anki2,media" {
+		t.Fatalf("package holds %v, want the collection and the media map", names)
+	}
+
+	db, err := sql.Open("sqlite", collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT n.guid, n.sfld, c.ord, c.did, n.mid, n.tags
+FROM cards c JOIN notes n ON n.id = c.nid
+ORDER BY n.id, c.ord`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var cards []ankiCard
+	for rows.Next() {
+		var card ankiCard
+		if err := rows.Scan(&card.guid, &card.swedish, &card.ord, &card.deckID, &card.modelID, &card.noteTags); err != nil {
+			t.Fatal(err)
+		}
+		cards = append(cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return cards
+}
+
+func TestAnki(t *testing.T) {
+	dict := writeTestDictionary(t, "engelska", "English", "swe_eng.xml", testExportXML)
+
+	tests := []struct {
+		name      string
+		opts      AnkiOptions
+		wantNotes int
+		wantCards []string // "<headword>/<template ord>"; "och" has no translations and never appears
+	}{
+		{
+			name:      "both directions",
+			wantNotes: 3,
+			wantCards: []string{"springa/0", "springa/1", "hus/0", "hus/1", "Åbo/0", "Åbo/1"},
+		},
+		{
+			name:      "reverse cards only",
+			opts:      AnkiOptions{Cards: []string{AnkiReverse}},
+			wantNotes: 3,
+			wantCards: []string{"springa/0", "hus/0", "Åbo/0"},
+		},
+		{
+			name:      "word class",
+			opts:      AnkiOptions{Cards: []string{AnkiForward}, WordClasses: []string{"Subst"}},
+			wantNotes: 1,
+			wantCards: []string{"hus/0"},
+		},
+		{
+			name:      "max rank",
+			opts:      AnkiOptions{Cards: []string{AnkiForward}, MaxRank: 120},
+			wantNotes: 2,
+			wantCards: []string{"springa/0", "hus/0"},
+		},
+		{
+			name:      "word class and max rank",
+			opts:      AnkiOptions{Cards: []string{AnkiForward}, WordClasses: []string{"verb", "namn"}, MaxRank: 1000},
+			wantNotes: 2,
+			wantCards: []string{"springa/0", "Åbo/0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "swe_eng.apkg")
+			count, err := Anki(path, dict, tt.opts)
+			if err != nil {
+				t.Fatalf("Anki: %v", err)
+			}
+			if count != tt.wantNotes {
+				t.Errorf("wrote %d notes, want %d", count, tt.wantNotes)
+			}
+
+			cards := readAnkiPackage(t, path)
+			var got []string
+			for _, card := range cards {
+				got = append(got, fmt.Sprintf("%s/%d", card.swedish, card.ord))
+				if card.deckID != ankiID(ankiDeckName(dict)) {
+					t.Errorf("%s is in deck %d, want %d", card.swedish, card.deckID, ankiID(ankiDeckName(dict)))
+				}
+				if !strings.Contains(card.noteTags, " swe_eng ") {
+					t.Errorf("%s has tags %q, want swe_eng among them", card.swedish, card.noteTags)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantCards) {
+				t.Errorf("cards = %v, want %v", got, tt.wantCards)
+			}
+		})
+	}
+}
+
+func TestAnkiNoteIdentityIsStable(t *testing.T) {
+	dict := writeTestDictionary(t, "engelska", "English", "swe_eng.xml", testExportXML)
+
+	// Two exports of the same dictionary must update the same notes on import
+	var runs [2][]ankiCard
+	for i := range runs {
+		path := filepath.Join(t.TempDir(), "swe_eng.apkg")
+		if _, err := Anki(path, dict, AnkiOptions{}); err != nil {
+			t.Fatalf("Anki: %v", err)
+		}
+		runs[i] = readAnkiPackage(t, path)
+	}
+
+	if len(runs[0]) == 0 || len(runs[0]) != len(runs[1]) {
+		t.Fatalf("exports have %d and %d cards", len(runs[0]), len(runs[1]))
+	}
+	for i := range runs[0] {
+		a, b := runs[0][i], runs[1][i]
+		if a.guid != b.guid || a.modelID != b.modelID || a.deckID != b.deckID {
+			t.Errorf("card %d differs between exports: %+v and %+v", i, a, b)
+		}
+	}
+	if runs[0][0].guid != "swe_eng:1:1" {
+		t.Errorf("guid = %q, want it built from the file, article and lemma ids", runs[0][0].guid)
+	}
+}
@@ -0,0 +1,294 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+// Row modes of a CSV export
+const (
+	CSVRowPerArticle     = "article"     // One row per article, senses joined with line breaks
+	CSVRowPerTranslation = "translation" // One row per translation of every sense
+)
+
+// DefaultCSVColumns are exported when no columns are selected
+var DefaultCSVColumns = []string{"lemma", "word_class", "inflection", "translation", "definition", "example"}
+
+// CSVOptions selects the layout of a CSV or TSV export
+type CSVOptions struct {
+	Columns    []string // Column names from CSVColumns; DefaultCSVColumns if empty
+	Rows       string   // CSVRowPerArticle (default) or CSVRowPerTranslation
+	Comma      rune     // Field separator, ',' for CSV or '\t' for TSV
+	BOM        bool     // Start the file with a UTF-8 byte order mark, so Excel detects the encoding
+	IsolateRTL bool     // Wrap right-to-left cells in Unicode isolates, so mixed-direction text keeps its order
+}
+
+// csvRow is the part of an article a row is built from
+type csvRow struct {
+	dict        catalog.Dictionary
+	article     *lexin.Article
+	lemmas      []*lexin.Lemma
+	lexemes     []*lexin.Lexeme
+	translation string // Set in per-translation mode
+	isolate     bool   // Wrap target-language text in right-to-left isolates
+}
+
+// CSVColumns lists the columns that can be selected, in their documented order
+var CSVColumns = []string{
+	"language", "article_id", "lexeme_id", "lemma", "word_class", "pronunciation", "hyphenation", "rank",
+	"inflection", "translation", "definition", "explanation", "example", "idiom", "compound", "comment",
+}
+
+// csvColumns render the cells of a row, by column name
+var csvColumns = map[string]func(r *csvRow) string{
+	"language":   func(r *csvRow) string { return r.dict.Language },
+	"article_id": func(r *csvRow) string { return r.article.ID },
+	"lexeme_id": func(r *csvRow) string {
+		return r.joinLexemes(", ", func(x *lexin.Lexeme) string { return x.ID })
+	},
+	"lemma": func(r *csvRow) string {
+		return r.joinLemmas(", ", func(l *lexin.Lemma) string { return l.Value })
+	},
+	"word_class": func(r *csvRow) string {
+		return r.joinLemmas(", ", func(l *lexin.Lemma) string { return l.WordClass })
+	},
+	"pronunciation": func(r *csvRow) string {
+		return r.joinLemmas(", ", func(l *lexin.Lemma) string { return l.Pronunciation() })
+	},
+	"hyphenation": func(r *csvRow) string {
+		return r.joinLemmas(", ", func(l *lexin.Lemma) string { return l.Hyphenate })
+	},
+	"rank": func(r *csvRow) string {
+		return r.joinLemmas(", ", func(l *lexin.Lemma) string {
+			if rank, ok := l.RankValue(); ok {
+				return strconv.Itoa(rank)
+			}
+			return ""
+		})
+	},
+	"inflection": func(r *csvRow) string {
+		return r.joinLemmas("; ", func(l *lexin.Lemma) string { return strings.Join(l.Forms(), ", ") })
+	},
+	"translation": func(r *csvRow) string {
+		if r.translation != "" {
+			return r.target(r.translation)
+		}
+		return r.joinLexemes("\n", func(x *lexin.Lexeme) string { return r.target(lexin.JoinTranslations(x.Translations)) })
+	},
+	"definition": func(r *csvRow) string {
+		return r.joinLexemes("\n", func(x *lexin.Lexeme) string { return x.Definition })
+	},
+	"explanation": func(r *csvRow) string {
+		return r.joinLexemes("\n", func(x *lexin.Lexeme) string { return x.Explanation })
+	},
+	"example": func(r *csvRow) string {
+		return r.joinLexemes("\n", func(x *lexin.Lexeme) string {
+			var lines []string
+			for _, example := range x.Examples {
+				lines = append(lines, r.pairLine(example.Value, example.Translations))
+			}
+			return strings.Join(lines, "\n")
+		})
+	},
+	"idiom": func(r *csvRow) string {
+		return r.joinLexemes("\n", func(x *lexin.Lexeme) string {
+			var lines []string
+			for _, idiom := range x.Idioms {
+				lines = append(lines, r.pairLine(idiom.Value, idiom.Translations))
+			}
+			return strings.Join(lines, "\n")
+		})
+	},
+	"compound": func(r *csvRow) string {
+		return r.joinLexemes("\n", func(x *lexin.Lexeme) string {
+			var lines []string
+			for _, compound := range x.Compounds {
+				lines = append(lines, r.pairLine(compound.Value, compound.Translations))
+			}
+			return strings.Join(lines, "\n")
+		})
+	},
+	"comment": func(r *csvRow) string {
+		return r.joinLexemes("\n", func(x *lexin.Lexeme) string { return x.Comment })
+	},
+}
+
+// joinLemmas joins the non-empty values of the row's lemmas
+func (r *csvRow) joinLemmas(sep string, value func(*lexin.Lemma) string) string {
+	var values []string
+	for _, lemma := range r.lemmas {
+		if v := value(lemma); v != "" {
+			values = append(values, v)
+		}
+	}
+	return strings.Join(values, sep)
+}
+
+// joinLexemes joins the non-empty values of the row's senses
+func (r *csvRow) joinLexemes(sep string, value func(*lexin.Lexeme) string) string {
+	var values []string
+	for _, lexeme := range r.lexemes {
+		if v := value(lexeme); v != "" {
+			values = append(values, v)
+		}
+	}
+	return strings.Join(values, sep)
+}
+
+// pairLine renders a Swedish phrase with its translations
+func (r *csvRow) pairLine(swedish string, translations []lexin.Translation) string {
+	if translated := lexin.JoinTranslations(translations); translated != "" {
+		return swedish + " — " + r.target(translated)
+	}
+	return swedish
+}
+
+// target wraps target-language text in RLI/PDI when isolation is on, so
+// spreadsheets keep the reading order of right-to-left text next to Swedish,
+// digits and punctuation
+func (r *csvRow) target(text string) string {
+	if !r.isolate || text == "" {
+		return text
+	}
+	// Isolates end at a line break, so every line gets its own
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "\u2067" + line + "\u2069"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CSV writes a dictionary as a CSV or TSV file with the selected columns.
+// Cells with line breaks, quotes or separators are quoted. It returns the number
+// of data rows; on failure the file is removed rather than left truncated.
+func CSV(path string, dict catalog.Dictionary, opts CSVOptions) (int, error) {
+	names := opts.Columns
+	if len(names) == 0 {
+		names = DefaultCSVColumns
+	}
+	columns := make([]func(*csvRow) string, len(names))
+	for i, name := range names {
+		column, ok := csvColumns[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown column %q (columns: %s)", name, strings.Join(CSVColumns, ", "))
+		}
+		columns[i] = column
+	}
+
+	switch opts.Rows {
+	case "", CSVRowPerArticle, CSVRowPerTranslation:
+	default:
+		return 0, fmt.Errorf("unknown row mode %q (modes: %s, %s)", opts.Rows, CSVRowPerArticle, CSVRowPerTranslation)
+	}
+
+	count, err := writeCSVFile(path, dict, opts, names, columns)
+	if err != nil {
+		os.Remove(path)
+		return count, err
+	}
+	return count, nil
+}
+
+// writeCSVFile creates path and writes the header and the rows to it
+func writeCSVFile(path string, dict catalog.Dictionary, opts CSVOptions, names []string, columns []func(*csvRow) string) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	if opts.BOM {
+		bw.WriteString("\uFEFF")
+	}
+
+	w := csv.NewWriter(bw)
+	if opts.Comma != 0 {
+		w.Comma = opts.Comma
+	}
+	if err := w.Write(names); err != nil {
+		return 0, err
+	}
+
+	isolate := opts.IsolateRTL && dict.RightToLeft()
+	record := make([]string, len(columns))
+	count := 0
+	write := func(row *csvRow) error {
+		row.isolate = isolate
+		for i, column := range columns {
+			record[i] = column(row)
+		}
+		count++
+		return w.Write(record)
+	}
+
+	for article, err := range lexin.Articles(dict.Path) {
+		if err != nil {
+			return count, fmt.Errorf("%s: %v", dict.File, err)
+		}
+
+		if opts.Rows != CSVRowPerTranslation {
+			row := &csvRow{dict: dict, article: article}
+			for i := range article.Lemmas {
+				lemma := &article.Lemmas[i]
+				row.lemmas = append(row.lemmas, lemma)
+				for j := range lemma.Lexemes {
+					row.lexemes = append(row.lexemes, &lemma.Lexemes[j])
+				}
+			}
+			if err := write(row); err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		// Senses and lemmas without translations still get a row, with an empty translation
+		for i := range article.Lemmas {
+			lemma := &article.Lemmas[i]
+			if len(lemma.Lexemes) == 0 {
+				if err := write(&csvRow{dict: dict, article: article, lemmas: []*lexin.Lemma{lemma}}); err != nil {
+					return count, err
+				}
+				continue
+			}
+			for j := range lemma.Lexemes {
+				lexeme := &lemma.Lexemes[j]
+				translations := lexeme.TranslationValues()
+				if len(translations) == 0 {
+					translations = []string{""}
+				}
+				for _, translation := range translations {
+					row := &csvRow{
+						dict:        dict,
+						article:     article,
+						lemmas:      []*lexin.Lemma{lemma},
+						lexemes:     []*lexin.Lexeme{lexeme},
+						translation: translation,
+					}
+					if err := write(row); err != nil {
+						return count, err
+					}
+				}
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return count, err
+	}
+	if err := bw.Flush(); err != nil {
+		return count, err
+	}
+
+	return count, f.Close()
+}
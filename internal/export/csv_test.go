@@ -0,0 +1,158 @@
+package export
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testArabicXML has a sense with two translations and an example
+const testArabicXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1">
+    <Lemma ID="1" Value="hus" Type="subst.">
+      <Lexeme ID="1">
+        <Translation>بيت</Translation>
+        <Translation>منزل</Translation>
+        <Example ID="1">ett stort hus<Translation>بيت كبير</Translation></Example>
+      </Lexeme>
+    </Lemma>
+  </Article>
+</Dictionary>
+`
+
+// readCSV exports a dictionary and reads the records back, header included
+func readCSV(t *testing.T, content string, code string, opts CSVOptions) [][]string {
+	t.Helper()
+
+	dict := writeTestDictionary(t, code, "Test", "swe_test.xml", content)
+	path := filepath.Join(t.TempDir(), "swe_test.csv")
+	count, err := CSV(path, dict, opts)
+	if err != nil {
+		t.Fatalf("CSV: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, hasBOM := strings.CutPrefix(string(data), "\uFEFF")
+	if hasBOM != opts.BOM {
+		t.Errorf("byte order mark present: %v, want %v", hasBOM, opts.BOM)
+	}
+
+	r := csv.NewReader(strings.NewReader(text))
+	if opts.Comma != 0 {
+		r.Comma = opts.Comma
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("reading the export back: %v", err)
+	}
+	if count != len(records)-1 {
+		t.Errorf("CSV reported %d rows, file has %d", count, len(records)-1)
+	}
+	return records
+}
+
+func TestCSVRows(t *testing.T) {
+	columns := []string{"lemma", "translation", "definition"}
+
+	tests := []struct {
+		name string
+		rows string
+		want [][]string
+	}{
+		{
+			name: "one row per article with senses on separate lines",
+			rows: CSVRowPerArticle,
+			want: [][]string{
+				columns,
+				{"springa", "run; race", "förflytta sig snabbt"},
+				{"hus", "house\nfamily", "familj"},
+				{"Åbo", "Turku", ""},
+				{"och", "", "binder samman ord"},
+			},
+		},
+		{
+			name: "one row per translation, keeping senses without any",
+			rows: CSVRowPerTranslation,
+			want: [][]string{
+				columns,
+				{"springa", "run", "förflytta sig snabbt"},
+				{"springa", "race", "förflytta sig snabbt"},
+				{"hus", "house", ""},
+				{"hus", "family", "familj"},
+				{"Åbo", "Turku", ""},
+				{"och", "", "binder samman ord"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readCSV(t, testExportXML, "engelska", CSVOptions{Columns: columns, Rows: tt.rows})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVIsolatesRightToLeftText(t *testing.T) {
+	const rli, pdi = "\u2067", "\u2069"
+	opts := CSVOptions{
+		Columns:    []string{"lemma", "translation", "example"},
+		Comma:      '\t',
+		BOM:        true,
+		IsolateRTL: true,
+	}
+
+	got := readCSV(t, testArabicXML, "arabiska", opts)
+	want := [][]string{
+		{"lemma", "translation", "example"},
+		{"hus", rli + "بيت; منزل" + pdi, "ett stort hus — " + rli + "بيت كبير" + pdi},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records =\n%q\nwant\n%q", got, want)
+	}
+
+	// Every line of a multi-line cell is isolated on its own
+	opts.Rows = CSVRowPerArticle
+	got = readCSV(t, strings.Replace(testArabicXML, `<Example`, `</Lexeme><Lexeme ID="2"><Translation>دار</Translation><Example`, 1), "arabiska", opts)
+	if cell := got[1][1]; cell != rli+"بيت; منزل"+pdi+"\n"+rli+"دار"+pdi {
+		t.Errorf("multi-line translation cell = %q", cell)
+	}
+
+	// Left-to-right languages are left alone
+	opts.Rows = ""
+	got = readCSV(t, testArabicXML, "engelska", opts)
+	if cell := got[1][1]; strings.ContainsAny(cell, rli+pdi) {
+		t.Errorf("translation of a left-to-right language isolated: %q", cell)
+	}
+}
+
+func TestCSVRemovesFileOnFailure(t *testing.T) {
+	// The second article is cut off, after the first row has been written
+	broken := `<Dictionary><Article ID="1"><Lemma Value="hus"/></Article><Article ID="2"><Lemma`
+	dict := writeTestDictionary(t, "engelska", "English", "swe_eng.xml", broken)
+	path := filepath.Join(t.TempDir(), "swe_eng.csv")
+
+	if _, err := CSV(path, dict, CSVOptions{}); err == nil {
+		t.Fatal("CSV succeeded for a truncated dictionary")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("truncated export left behind: %v", err)
+	}
+
+	// Bad options fail before anything is created
+	if _, err := CSV(path, dict, CSVOptions{Columns: []string{"lemma", "colour"}}); err == nil || !strings.Contains(err.Error(), "colour") {
+		t.Errorf("CSV with an unknown column = %v, want an error naming it", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file created for an unknown column: %v", err)
+	}
+}
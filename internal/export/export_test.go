@@ -0,0 +1,67 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"getlexin-xml/internal/catalog"
+)
+
+// testExportXML is a small dictionary shared by the export tests: a verb with
+// inflections, examples, an idiom and a compound, a noun with two senses, a
+// capitalized headword and a headword without translations or rank
+const testExportXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1">
+    <Lemma ID="1" Value="springa" Type="verb" Rank="120">
+      <Phonetic>spr'ing:a</Phonetic>
+      <Inflection>sprang</Inflection>
+      <Inflection>sprungit</Inflection>
+      <Lexeme ID="1">
+        <Definition>förflytta sig snabbt</Definition>
+        <Translation>run</Translation>
+        <Translation>race</Translation>
+        <Example ID="1">springa fort<Translation>run fast</Translation></Example>
+        <Idiom ID="1">springa benen av sig<Translation>run one's legs off</Translation></Idiom>
+        <Compound ID="1">springpojke<Translation>errand boy</Translation></Compound>
+      </Lexeme>
+      <Reference Type="compare" Value="löpa"/>
+    </Lemma>
+  </Article>
+  <Article ID="2">
+    <Lemma ID="2" Value="hus" Type="subst." Rank="40">
+      <Inflection>huset</Inflection>
+      <Lexeme ID="2"><Translation>house</Translation></Lexeme>
+      <Lexeme ID="3"><Definition>familj</Definition><Translation>family</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="3">
+    <Lemma ID="3" Value="Åbo" Type="namn" Rank="900">
+      <Lexeme ID="4"><Translation>Turku</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="4">
+    <Lemma ID="4" Value="och" Type="konj.">
+      <Lexeme ID="5"><Definition>binder samman ord</Definition></Lexeme>
+    </Lemma>
+  </Article>
+</Dictionary>
+`
+
+// writeTestDictionary writes content as the dictionary file of a language in a temporary directory
+func writeTestDictionary(t *testing.T, code, language, file, content string) catalog.Dictionary {
+	t.Helper()
+
+	dict := catalog.Dictionary{
+		Code:     code,
+		Language: language,
+		File:     file,
+		Path:     filepath.Join(t.TempDir(), file),
+		Revision: "1234",
+	}
+	if err := os.WriteFile(dict.Path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dict
+}
@@ -0,0 +1,95 @@
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+// JSONLSchemaVersion is bumped whenever a JSONL record changes incompatibly
+const JSONLSchemaVersion = 1
+
+// JSONLRecord is one line of a JSONL export: an article with its provenance.
+// The article fields (id, lemmas) are inlined next to schema and source,
+// using the JSON names of the pkg/lexin types.
+type JSONLRecord struct {
+	Schema int         `json:"schema"`
+	Source JSONLSource `json:"source"`
+	*lexin.Article
+}
+
+// JSONLSource identifies the file and revision an article was read from
+type JSONLSource struct {
+	Pair     string `json:"pair"`     // Language pair, e.g. "swe_eng"
+	Code     string `json:"code"`     // Language directory code, e.g. "engelska"
+	Language string `json:"language"` // Human-readable language name
+	File     string `json:"file"`
+	Revision string `json:"revision,omitempty"`
+}
+
+// JSONL writes one JSON object per article of a dictionary to path,
+// gzip-compressed if compress is set. It returns the number of articles written.
+func JSONL(path string, dict catalog.Dictionary, compress bool) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+
+	bw := bufio.NewWriter(w)
+	count, err := writeJSONL(bw, dict)
+	if err != nil {
+		return count, err
+	}
+	if err := bw.Flush(); err != nil {
+		return count, err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return count, err
+		}
+	}
+
+	return count, f.Close()
+}
+
+// writeJSONL streams the records of a dictionary to w
+func writeJSONL(w io.Writer, dict catalog.Dictionary) (int, error) {
+	source := JSONLSource{
+		Pair:     dict.ID(),
+		Code:     dict.Code,
+		Language: dict.Language,
+		File:     dict.File,
+		Revision: dict.Revision,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	count := 0
+	for article, err := range lexin.Articles(dict.Path) {
+		if err != nil {
+			return count, fmt.Errorf("%s: %v", dict.File, err)
+		}
+		record := JSONLRecord{Schema: JSONLSchemaVersion, Source: source, Article: article}
+		if err := encoder.Encode(record); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
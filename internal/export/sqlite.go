@@ -0,0 +1,278 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver, no cgo needed
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+// sqliteSchema creates the normalized tables and the full-text index
+const sqliteSchema = `
+CREATE TABLE languages (
+	id       INTEGER PRIMARY KEY,
+	code     TEXT NOT NULL,
+	name     TEXT NOT NULL,
+	pair     TEXT NOT NULL UNIQUE,
+	file     TEXT NOT NULL,
+	revision TEXT NOT NULL
+);
+
+CREATE TABLE lemmas (
+	id          INTEGER PRIMARY KEY,
+	language_id INTEGER NOT NULL REFERENCES languages(id),
+	article_id  TEXT NOT NULL,
+	lemma_id    TEXT NOT NULL,
+	headword    TEXT NOT NULL,
+	word_class  TEXT NOT NULL,
+	hyphenation TEXT NOT NULL,
+	phonetic    TEXT NOT NULL,
+	rank        INTEGER
+);
+CREATE INDEX lemmas_headword ON lemmas(headword);
+CREATE INDEX lemmas_language ON lemmas(language_id);
+
+CREATE TABLE inflections (
+	id       INTEGER PRIMARY KEY,
+	lemma_id INTEGER NOT NULL REFERENCES lemmas(id),
+	form     TEXT NOT NULL,
+	value    TEXT NOT NULL
+);
+CREATE INDEX inflections_value ON inflections(value);
+
+CREATE TABLE lexemes (
+	id          INTEGER PRIMARY KEY,
+	lemma_id    INTEGER NOT NULL REFERENCES lemmas(id),
+	lexeme_id   TEXT NOT NULL,
+	definition  TEXT NOT NULL,
+	explanation TEXT NOT NULL,
+	comment     TEXT NOT NULL
+);
+CREATE INDEX lexemes_lemma ON lexemes(lemma_id);
+
+CREATE TABLE translations (
+	id        INTEGER PRIMARY KEY,
+	lexeme_id INTEGER NOT NULL REFERENCES lexemes(id),
+	value     TEXT NOT NULL,
+	comment   TEXT NOT NULL
+);
+CREATE INDEX translations_lexeme ON translations(lexeme_id);
+
+CREATE TABLE examples (
+	id          INTEGER PRIMARY KEY,
+	lexeme_id   INTEGER NOT NULL REFERENCES lexemes(id),
+	swedish     TEXT NOT NULL,
+	translation TEXT NOT NULL
+);
+CREATE INDEX examples_lexeme ON examples(lexeme_id);
+
+CREATE TABLE idioms (
+	id          INTEGER PRIMARY KEY,
+	lexeme_id   INTEGER NOT NULL REFERENCES lexemes(id),
+	swedish     TEXT NOT NULL,
+	definition  TEXT NOT NULL,
+	translation TEXT NOT NULL
+);
+CREATE INDEX idioms_lexeme ON idioms(lexeme_id);
+
+CREATE TABLE compounds (
+	id          INTEGER PRIMARY KEY,
+	lexeme_id   INTEGER NOT NULL REFERENCES lexemes(id),
+	swedish     TEXT NOT NULL,
+	translation TEXT NOT NULL
+);
+CREATE INDEX compounds_lexeme ON compounds(lexeme_id);
+
+CREATE TABLE cross_references (
+	id       INTEGER PRIMARY KEY,
+	lemma_id INTEGER NOT NULL REFERENCES lemmas(id),
+	type     TEXT NOT NULL,
+	value    TEXT NOT NULL
+);
+
+-- One row per lemma: Swedish headword and forms, and all target-language translations
+CREATE VIRTUAL TABLE lemmas_fts USING fts5(
+	headword,
+	translations,
+	tokenize='unicode61 remove_diacritics 0'
+);
+`
+
+// SQLite writes the dictionaries into a new SQLite database at path, replacing any existing file
+func SQLite(path string, dictionaries []catalog.Dictionary) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("failed to create schema: %v", err)
+	}
+
+	for _, dict := range dictionaries {
+		if err := exportSQLiteDictionary(db, dict); err != nil {
+			return fmt.Errorf("%s: %v", dict.File, err)
+		}
+	}
+
+	return nil
+}
+
+// sqliteWriter holds the prepared statements of one export transaction
+type sqliteWriter struct {
+	tx           *sql.Tx
+	lemma        *sql.Stmt
+	inflection   *sql.Stmt
+	lexeme       *sql.Stmt
+	translation  *sql.Stmt
+	example      *sql.Stmt
+	idiom        *sql.Stmt
+	compound     *sql.Stmt
+	reference    *sql.Stmt
+	fullText     *sql.Stmt
+	languageID   int64
+	prepareError error
+}
+
+// prepare adds a statement to the writer, remembering the first error
+func (w *sqliteWriter) prepare(query string) *sql.Stmt {
+	if w.prepareError != nil {
+		return nil
+	}
+	stmt, err := w.tx.Prepare(query)
+	if err != nil {
+		w.prepareError = err
+	}
+	return stmt
+}
+
+// exportSQLiteDictionary streams one dictionary file into the database in a single transaction
+func exportSQLiteDictionary(db *sql.DB, dict catalog.Dictionary) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO languages (code, name, pair, file, revision) VALUES (?, ?, ?, ?, ?)`,
+		dict.Code, dict.Language, dict.ID(), dict.File, dict.Revision)
+	if err != nil {
+		return err
+	}
+
+	w := &sqliteWriter{tx: tx}
+	w.languageID, _ = res.LastInsertId()
+	w.lemma = w.prepare(`INSERT INTO lemmas (language_id, article_id, lemma_id, headword, word_class, hyphenation, phonetic, rank) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	w.inflection = w.prepare(`INSERT INTO inflections (lemma_id, form, value) VALUES (?, ?, ?)`)
+	w.lexeme = w.prepare(`INSERT INTO lexemes (lemma_id, lexeme_id, definition, explanation, comment) VALUES (?, ?, ?, ?, ?)`)
+	w.translation = w.prepare(`INSERT INTO translations (lexeme_id, value, comment) VALUES (?, ?, ?)`)
+	w.example = w.prepare(`INSERT INTO examples (lexeme_id, swedish, translation) VALUES (?, ?, ?)`)
+	w.idiom = w.prepare(`INSERT INTO idioms (lexeme_id, swedish, definition, translation) VALUES (?, ?, ?, ?)`)
+	w.compound = w.prepare(`INSERT INTO compounds (lexeme_id, swedish, translation) VALUES (?, ?, ?)`)
+	w.reference = w.prepare(`INSERT INTO cross_references (lemma_id, type, value) VALUES (?, ?, ?)`)
+	w.fullText = w.prepare(`INSERT INTO lemmas_fts (rowid, headword, translations) VALUES (?, ?, ?)`)
+	if w.prepareError != nil {
+		return w.prepareError
+	}
+
+	for article, err := range lexin.Articles(dict.Path) {
+		if err != nil {
+			return err
+		}
+		if err := w.writeArticle(article); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// writeArticle inserts every lemma of an article with its senses
+func (w *sqliteWriter) writeArticle(article *lexin.Article) error {
+	for _, lemma := range article.Lemmas {
+		var rank any
+		if r, ok := lemma.RankValue(); ok {
+			rank = r
+		}
+
+		res, err := w.lemma.Exec(w.languageID, article.ID, lemma.ID, lemma.Value, lemma.WordClass, lemma.Hyphenate, lemma.Pronunciation(), rank)
+		if err != nil {
+			return err
+		}
+		lemmaRow, _ := res.LastInsertId()
+
+		for _, inflection := range lemma.Inflections {
+			if _, err := w.inflection.Exec(lemmaRow, inflection.Form, inflection.Value); err != nil {
+				return err
+			}
+		}
+		for _, ref := range lemma.References {
+			if _, err := w.reference.Exec(lemmaRow, ref.Type, ref.Value); err != nil {
+				return err
+			}
+		}
+
+		var translations []string
+		for _, lexeme := range lemma.Lexemes {
+			if err := w.writeLexeme(lemmaRow, lexeme); err != nil {
+				return err
+			}
+			translations = append(translations, lexeme.TranslationValues()...)
+		}
+
+		// Index the headword with its forms so inflected lookups match too
+		headwords := append([]string{lemma.Value}, lemma.Forms()...)
+		if _, err := w.fullText.Exec(lemmaRow, strings.Join(headwords, " "), strings.Join(translations, " ; ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeLexeme inserts one sense with its translations, examples, idioms, compounds and references
+func (w *sqliteWriter) writeLexeme(lemmaRow int64, lexeme lexin.Lexeme) error {
+	res, err := w.lexeme.Exec(lemmaRow, lexeme.ID, lexeme.Definition, lexeme.Explanation, lexeme.Comment)
+	if err != nil {
+		return err
+	}
+	lexemeRow, _ := res.LastInsertId()
+
+	for _, t := range lexeme.Translations {
+		if _, err := w.translation.Exec(lexemeRow, t.Value, t.Comment); err != nil {
+			return err
+		}
+	}
+	for _, example := range lexeme.Examples {
+		if _, err := w.example.Exec(lexemeRow, example.Value, lexin.JoinTranslations(example.Translations)); err != nil {
+			return err
+		}
+	}
+	for _, idiom := range lexeme.Idioms {
+		if _, err := w.idiom.Exec(lexemeRow, idiom.Value, idiom.Definition, lexin.JoinTranslations(idiom.Translations)); err != nil {
+			return err
+		}
+	}
+	for _, compound := range lexeme.Compounds {
+		if _, err := w.compound.Exec(lexemeRow, compound.Value, lexin.JoinTranslations(compound.Translations)); err != nil {
+			return err
+		}
+	}
+	for _, ref := range lexeme.References {
+		if _, err := w.reference.Exec(lemmaRow, ref.Type, ref.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
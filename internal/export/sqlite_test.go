@@ -0,0 +1,89 @@
+package export
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"getlexin-xml/internal/catalog"
+)
+
+func TestSQLite(t *testing.T) {
+	dict := writeTestDictionary(t, "engelska", "English", "swe_eng.xml", testExportXML)
+	path := filepath.Join(t.TempDir(), "lexin.sqlite")
+
+	if err := SQLite(path, []catalog.Dictionary{dict}); err != nil {
+		t.Fatalf("SQLite: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Every entity has its own table
+	counts := []struct {
+		table string
+		want  int
+	}{
+		{"languages", 1},
+		{"lemmas", 4},
+		{"inflections", 3},
+		{"lexemes", 5},
+		{"translations", 5},
+		{"examples", 1},
+		{"idioms", 1},
+		{"compounds", 1},
+		{"cross_references", 1},
+		{"lemmas_fts", 4},
+	}
+	for _, tt := range counts {
+		var got int
+		if err := db.QueryRow("SELECT count(*) FROM " + tt.table).Scan(&got); err != nil {
+			t.Fatalf("counting %s: %v", tt.table, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s has %d rows, want %d", tt.table, got, tt.want)
+		}
+	}
+
+	var compound string
+	if err := db.QueryRow(`SELECT translation FROM compounds WHERE swedish = 'springpojke'`).Scan(&compound); err != nil {
+		t.Fatal(err)
+	}
+	if compound != "errand boy" {
+		t.Errorf("compound translation = %q, want %q", compound, "errand boy")
+	}
+
+	// The full-text queries from the README: an inflected form finds its headword,
+	// and so does a translation
+	queries := []struct {
+		match string
+		want  string
+	}{
+		{"sprang", "springa"},
+		{"translations:family", "hus"},
+		{"åbo", "Åbo"},
+	}
+	for _, tt := range queries {
+		rows, err := db.Query(`SELECT l.headword, t.translations
+FROM lemmas_fts t JOIN lemmas l ON l.id = t.rowid
+WHERE lemmas_fts MATCH ?`, tt.match)
+		if err != nil {
+			t.Fatalf("MATCH %q: %v", tt.match, err)
+		}
+		var headwords []string
+		for rows.Next() {
+			var headword, translations string
+			if err := rows.Scan(&headword, &translations); err != nil {
+				t.Fatal(err)
+			}
+			headwords = append(headwords, headword)
+		}
+		rows.Close()
+		if len(headwords) != 1 || headwords[0] != tt.want {
+			t.Errorf("MATCH %q found %v, want [%s]", tt.match, headwords, tt.want)
+		}
+	}
+}
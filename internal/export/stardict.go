@@ -0,0 +1,284 @@
+package export
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+// dictzipChunkSize is the uncompressed size of each independently compressed
+// chunk in a .dict.dz file, the same value dictzip uses
+const dictzipChunkSize = 58315
+
+// stardictMaxWordLength is the longest word, in bytes, a StarDict index entry may hold
+const stardictMaxWordLength = 255
+
+// StarDictStats summarizes an exported StarDict bundle
+type StarDictStats struct {
+	Words    int // Entries in the .idx file
+	Synonyms int // Inflected forms in the .syn file
+}
+
+// stardictEntry is a headword with the location of its definition in the .dict data
+type stardictEntry struct {
+	word   string
+	offset uint32
+	size   uint32
+}
+
+// stardictSynonym maps an inflected form to the lemma it belongs to
+type stardictSynonym struct {
+	word  string
+	entry int // Index of the lemma entry before sorting
+}
+
+// StarDict writes a dictionary as a StarDict bundle (.ifo, .idx, .dict.dz and .syn)
+// into dir. Inflected forms go into the .syn file so readers resolve them to the lemma.
+func StarDict(dir string, dict catalog.Dictionary) (StarDictStats, error) {
+	var stats StarDictStats
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return stats, err
+	}
+
+	var data bytes.Buffer
+	var entries []stardictEntry
+	var synonyms []stardictSynonym
+
+	for article, err := range lexin.Articles(dict.Path) {
+		if err != nil {
+			return stats, fmt.Errorf("%s: %v", dict.File, err)
+		}
+
+		for _, lemma := range article.Lemmas {
+			if lemma.Value == "" || len(lemma.Value) > stardictMaxWordLength {
+				continue
+			}
+
+			definition := stardictDefinition(&lemma)
+			entries = append(entries, stardictEntry{
+				word:   lemma.Value,
+				offset: uint32(data.Len()),
+				size:   uint32(len(definition)),
+			})
+			data.WriteString(definition)
+
+			seen := map[string]bool{lemma.Value: true}
+			for _, form := range lemma.Forms() {
+				if seen[form] || len(form) > stardictMaxWordLength {
+					continue
+				}
+				seen[form] = true
+				synonyms = append(synonyms, stardictSynonym{word: form, entry: len(entries) - 1})
+			}
+		}
+	}
+
+	// Readers binary-search the index, so entries must be in StarDict order;
+	// synonyms point at the sorted position of their lemma
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return stardictCompare(entries[order[i]].word, entries[order[j]].word) < 0
+	})
+	position := make([]uint32, len(entries))
+	for sorted, original := range order {
+		position[original] = uint32(sorted)
+	}
+	sort.SliceStable(synonyms, func(i, j int) bool {
+		return stardictCompare(synonyms[i].word, synonyms[j].word) < 0
+	})
+
+	var idx bytes.Buffer
+	for _, i := range order {
+		idx.WriteString(entries[i].word)
+		idx.WriteByte(0)
+		binary.Write(&idx, binary.BigEndian, entries[i].offset)
+		binary.Write(&idx, binary.BigEndian, entries[i].size)
+	}
+
+	var syn bytes.Buffer
+	for _, s := range synonyms {
+		syn.WriteString(s.word)
+		syn.WriteByte(0)
+		binary.Write(&syn, binary.BigEndian, position[s.entry])
+	}
+
+	base := filepath.Join(dir, dict.ID())
+	if err := os.WriteFile(base+".idx", idx.Bytes(), 0644); err != nil {
+		return stats, err
+	}
+	if err := os.WriteFile(base+".syn", syn.Bytes(), 0644); err != nil {
+		return stats, err
+	}
+	if err := writeDictzip(base+".dict.dz", data.Bytes()); err != nil {
+		return stats, err
+	}
+
+	stats = StarDictStats{Words: len(entries), Synonyms: len(synonyms)}
+	ifo := stardictInfo(dict, stats, idx.Len())
+	if err := os.WriteFile(base+".ifo", []byte(ifo), 0644); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// stardictInfo renders the .ifo file describing the bundle
+func stardictInfo(dict catalog.Dictionary, stats StarDictStats, idxSize int) string {
+	description := "Lexin Swedish-" + dict.Language + " dictionary, Institutet för språk och folkminnen"
+	if dict.Revision != "" {
+		description += ", revision " + dict.Revision
+	}
+
+	var b strings.Builder
+	b.WriteString("StarDict's dict ifo file\n")
+	b.WriteString("version=2.4.2\n")
+	fmt.Fprintf(&b, "bookname=Lexin svenska-%s\n", dict.Language)
+	fmt.Fprintf(&b, "wordcount=%d\n", stats.Words)
+	fmt.Fprintf(&b, "synwordcount=%d\n", stats.Synonyms)
+	fmt.Fprintf(&b, "idxfilesize=%d\n", idxSize)
+	b.WriteString("author=Institutet för språk och folkminnen\n")
+	b.WriteString("website=https://sprakresurser.isof.se/lexin/\n")
+	fmt.Fprintf(&b, "description=%s\n", description)
+	fmt.Fprintf(&b, "date=%s\n", time.Now().Format("2006.01.02"))
+	b.WriteString("sametypesequence=h\n")
+	return b.String()
+}
+
+// stardictDefinition renders a lemma as the HTML shown by the dictionary reader
+func stardictDefinition(lemma *lexin.Lemma) string {
+	var b strings.Builder
+	esc := html.EscapeString
+
+	fmt.Fprintf(&b, "<b>%s</b>", esc(lemma.Value))
+	if p := lemma.Pronunciation(); p != "" {
+		fmt.Fprintf(&b, " [%s]", esc(p))
+	}
+	if lemma.WordClass != "" {
+		fmt.Fprintf(&b, " <i>%s</i>", esc(lemma.WordClass))
+	}
+	if forms := lemma.Forms(); len(forms) > 0 {
+		fmt.Fprintf(&b, "<br>%s", esc(strings.Join(forms, ", ")))
+	}
+
+	for i, lexeme := range lemma.Lexemes {
+		b.WriteString("<br>")
+		if len(lemma.Lexemes) > 1 {
+			fmt.Fprintf(&b, "<b>%d.</b> ", i+1)
+		}
+		if lexeme.Definition != "" {
+			fmt.Fprintf(&b, "%s ", esc(lexeme.Definition))
+		}
+		if translations := lexin.JoinTranslations(lexeme.Translations); translations != "" {
+			fmt.Fprintf(&b, "<b>%s</b>", esc(translations))
+		}
+		for _, example := range lexeme.Examples {
+			fmt.Fprintf(&b, "<br>&nbsp;&nbsp;<i>%s</i> – %s", esc(example.Value), esc(lexin.JoinTranslations(example.Translations)))
+		}
+		for _, idiom := range lexeme.Idioms {
+			fmt.Fprintf(&b, "<br>&nbsp;&nbsp;<i>%s</i> – %s", esc(idiom.Value), esc(lexin.JoinTranslations(idiom.Translations)))
+		}
+		for _, compound := range lexeme.Compounds {
+			fmt.Fprintf(&b, "<br>&nbsp;&nbsp;<i>%s</i> – %s", esc(compound.Value), esc(lexin.JoinTranslations(compound.Translations)))
+		}
+	}
+
+	return b.String()
+}
+
+// stardictCompare orders words the way StarDict does: ASCII case-insensitively,
+// falling back to a byte comparison for words that only differ in case
+func stardictCompare(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		ca, cb := asciiLower(a[i]), asciiLower(b[i])
+		if ca != cb {
+			return int(ca) - int(cb)
+		}
+	}
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+func asciiLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
+
+// writeDictzip writes data as a dictzip file: a gzip file whose deflate stream
+// is split into independently compressed chunks, listed in the header so readers
+// can seek to any definition without decompressing the whole file
+func writeDictzip(path string, data []byte) error {
+	var body bytes.Buffer
+	var chunkSizes []uint16
+
+	chunks := (len(data) + dictzipChunkSize - 1) / dictzipChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	for i := 0; i < chunks; i++ {
+		chunk := data[min(i*dictzipChunkSize, len(data)):min((i+1)*dictzipChunkSize, len(data))]
+
+		start := body.Len()
+		// A fresh compressor per chunk keeps back-references inside the chunk
+		zw, err := flate.NewWriter(&body, flate.BestCompression)
+		if err != nil {
+			return err
+		}
+		if _, err := zw.Write(chunk); err != nil {
+			return err
+		}
+		if i == chunks-1 {
+			err = zw.Close()
+		} else {
+			err = zw.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		chunkSizes = append(chunkSizes, uint16(body.Len()-start))
+	}
+
+	// "RA" extra field: version, chunk length, chunk count, compressed chunk sizes
+	var extra bytes.Buffer
+	extra.WriteString("RA")
+	binary.Write(&extra, binary.LittleEndian, uint16(6+2*len(chunkSizes)))
+	binary.Write(&extra, binary.LittleEndian, uint16(1))
+	binary.Write(&extra, binary.LittleEndian, uint16(dictzipChunkSize))
+	binary.Write(&extra, binary.LittleEndian, uint16(len(chunkSizes)))
+	for _, size := range chunkSizes {
+		binary.Write(&extra, binary.LittleEndian, size)
+	}
+	if extra.Len() > 0xffff {
+		return fmt.Errorf("dictionary too large for dictzip (%d bytes)", len(data))
+	}
+
+	var out bytes.Buffer
+	out.Write([]byte{0x1f, 0x8b, 8, 4}) // gzip magic, deflate, FEXTRA
+	binary.Write(&out, binary.LittleEndian, uint32(time.Now().Unix()))
+	out.Write([]byte{2, 3}) // best compression, Unix
+	binary.Write(&out, binary.LittleEndian, uint16(extra.Len()))
+	out.Write(extra.Bytes())
+	out.Write(body.Bytes())
+	binary.Write(&out, binary.LittleEndian, crc32.ChecksumIEEE(data))
+	binary.Write(&out, binary.LittleEndian, uint32(len(data)))
+
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
@@ -0,0 +1,221 @@
+package export
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readStarDictIndex parses .idx entries (word, big-endian offset and size)
+func readStarDictIndex(t *testing.T, data []byte) []stardictEntry {
+	t.Helper()
+
+	var entries []stardictEntry
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, 0)
+		if end == -1 || len(data) < end+9 {
+			t.Fatalf("truncated .idx entry %q", data)
+		}
+		entries = append(entries, stardictEntry{
+			word:   string(data[:end]),
+			offset: binary.BigEndian.Uint32(data[end+1:]),
+			size:   binary.BigEndian.Uint32(data[end+5:]),
+		})
+		data = data[end+9:]
+	}
+	return entries
+}
+
+// readStarDictSynonyms parses .syn entries (word and big-endian index into the .idx)
+func readStarDictSynonyms(t *testing.T, data []byte) []stardictSynonym {
+	t.Helper()
+
+	var synonyms []stardictSynonym
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, 0)
+		if end == -1 || len(data) < end+5 {
+			t.Fatalf("truncated .syn entry %q", data)
+		}
+		synonyms = append(synonyms, stardictSynonym{
+			word:  string(data[:end]),
+			entry: int(binary.BigEndian.Uint32(data[end+1:])),
+		})
+		data = data[end+5:]
+	}
+	return synonyms
+}
+
+func TestStarDict(t *testing.T) {
+	dict := writeTestDictionary(t, "engelska", "English", "swe_eng.xml", testExportXML)
+	dir := t.TempDir()
+
+	stats, err := StarDict(dir, dict)
+	if err != nil {
+		t.Fatalf("StarDict: %v", err)
+	}
+	if stats.Words != 4 || stats.Synonyms != 3 {
+		t.Errorf("stats = %+v, want 4 words and 3 synonyms", stats)
+	}
+
+	base := filepath.Join(dir, "swe_eng")
+	idx, err := os.ReadFile(base + ".idx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	syn, err := os.ReadFile(base + ".syn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifo, err := os.ReadFile(base + ".ifo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The .ifo describes the other files
+	for _, want := range []string{"wordcount=4\n", "synwordcount=3\n", fmt.Sprintf("idxfilesize=%d\n", len(idx)), "sametypesequence=h\n"} {
+		if !strings.Contains(string(ifo), want) {
+			t.Errorf(".ifo does not contain %q:\n%s", want, ifo)
+		}
+	}
+
+	// The index is in StarDict order, with non-ASCII headwords after the ASCII ones
+	entries := readStarDictIndex(t, idx)
+	var words []string
+	for _, entry := range entries {
+		words = append(words, entry.word)
+	}
+	if got := strings.Join(words, ","); got != "hus,och,springa,Åbo" {
+		t.Fatalf(".idx words = %s, want hus,och,springa,Åbo", got)
+	}
+
+	// Every offset and size points at the definition of its word in the gunzipped data
+	dz, err := os.Open(base + ".dict.dz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dz.Close()
+	gz, err := gzip.NewReader(dz)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("gunzip .dict.dz: %v", err)
+	}
+	for _, entry := range entries {
+		end := int(entry.offset + entry.size)
+		if end > len(data) {
+			t.Fatalf("%s: offset %d and size %d past the %d bytes of data", entry.word, entry.offset, entry.size, len(data))
+		}
+		definition := string(data[entry.offset:end])
+		if !strings.HasPrefix(definition, "<b>"+entry.word+"</b>") {
+			t.Errorf("definition of %s = %q", entry.word, definition)
+		}
+	}
+
+	// Inflected forms point at the sorted position of their lemma
+	want := map[string]string{"huset": "hus", "sprang": "springa", "sprungit": "springa"}
+	synonyms := readStarDictSynonyms(t, syn)
+	if len(synonyms) != len(want) {
+		t.Fatalf("got %d synonyms, want %d", len(synonyms), len(want))
+	}
+	for i, s := range synonyms {
+		if s.entry >= len(entries) {
+			t.Fatalf("synonym %s points at entry %d of %d", s.word, s.entry, len(entries))
+		}
+		if got := entries[s.entry].word; got != want[s.word] {
+			t.Errorf("synonym %s resolves to %q, want %q", s.word, got, want[s.word])
+		}
+		if i > 0 && stardictCompare(synonyms[i-1].word, s.word) > 0 {
+			t.Errorf(".syn is not sorted: %s before %s", synonyms[i-1].word, s.word)
+		}
+	}
+}
+
+func TestStarDictCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // Sign of the comparison
+	}{
+		{"hus", "hus", 0},
+		{"Hus", "hus", -1}, // Same letters, so the bytes decide
+		{"hus", "Huset", -1},
+		{"Zebra", "apa", 1},
+		{"zebra", "Åbo", -1},
+		{"bil", "bi", 1},
+	}
+
+	for _, tt := range tests {
+		got := stardictCompare(tt.a, tt.b)
+		if (got < 0 && tt.want >= 0) || (got > 0 && tt.want <= 0) || (got == 0 && tt.want != 0) {
+			t.Errorf("stardictCompare(%q, %q) = %d, want the sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWriteDictzipChunks(t *testing.T) {
+	// Enough barely compressible data for three chunks
+	data := make([]byte, 2*dictzipChunkSize+1000)
+	rand.New(rand.NewSource(1)).Read(data)
+	for i := range data {
+		data[i] = 'a' + data[i]%16
+	}
+
+	path := filepath.Join(t.TempDir(), "test.dict.dz")
+	if err := writeDictzip(path, data); err != nil {
+		t.Fatalf("writeDictzip: %v", err)
+	}
+	dz, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A plain gzip reader sees the whole file
+	gz, err := gzip.NewReader(bytes.NewReader(dz))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if got, err := io.ReadAll(gz); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("gunzipped %d bytes (%v), want the %d bytes written", len(got), err, len(data))
+	}
+
+	// The "RA" extra field lists the chunks
+	if dz[3]&4 == 0 {
+		t.Fatal("FEXTRA flag not set")
+	}
+	xlen := int(binary.LittleEndian.Uint16(dz[10:]))
+	extra := dz[12 : 12+xlen]
+	if string(extra[:2]) != "RA" {
+		t.Fatalf("extra field id = %q, want RA", extra[:2])
+	}
+	chunkLength := binary.LittleEndian.Uint16(extra[6:])
+	chunkCount := int(binary.LittleEndian.Uint16(extra[8:]))
+	if chunkLength != dictzipChunkSize || chunkCount != 3 {
+		t.Fatalf("chunk length %d and count %d, want %d and 3", chunkLength, chunkCount, dictzipChunkSize)
+	}
+
+	// Each chunk inflates on its own, as a reader seeking to a definition does
+	offset := 12 + xlen
+	for i := range chunkCount {
+		size := int(binary.LittleEndian.Uint16(extra[10+2*i:]))
+		// Chunks before the last end in a sync flush rather than a final block
+		chunk, err := io.ReadAll(flate.NewReader(bytes.NewReader(dz[offset : offset+size])))
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+
+		want := data[i*dictzipChunkSize : min((i+1)*dictzipChunkSize, len(data))]
+		if !bytes.Equal(chunk, want) {
+			t.Errorf("chunk %d inflates to %d bytes, want %d", i, len(chunk), len(want))
+		}
+		offset += size
+	}
+}
@@ -0,0 +1,577 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+const (
+	teiNamespace = "http://www.tei-c.org/ns/1.0"
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+// teiPartOfSpeech maps Lexin word classes to Universal Dependencies tags,
+// which TEI Lex-0 recommends for the norm attribute of <gram type="pos">
+var teiPartOfSpeech = map[string]string{
+	"subst":   "NOUN",
+	"verb":    "VERB",
+	"adj":     "ADJ",
+	"adv":     "ADV",
+	"pron":    "PRON",
+	"prep":    "ADP",
+	"konj":    "CCONJ",
+	"subj":    "SCONJ",
+	"interj":  "INTJ",
+	"räkn":    "NUM",
+	"artikel": "DET",
+	"förk":    "X",
+}
+
+type teiEntry struct {
+	XMLName xml.Name    `xml:"entry"`
+	ID      string      `xml:"xml:id,attr"`
+	Lang    string      `xml:"xml:lang,attr,omitempty"`
+	Type    string      `xml:"type,attr,omitempty"`
+	Forms   []teiForm   `xml:"form"`
+	GramGrp *teiGramGrp `xml:"gramGrp"`
+	Notes   []teiNote   `xml:"note"`
+	Senses  []teiSense  `xml:"sense"`
+	Xrs     []teiXr     `xml:"xr"`
+}
+
+type teiForm struct {
+	Type    string      `xml:"type,attr"`
+	GramGrp *teiGramGrp `xml:"gramGrp"`
+	Orth    string      `xml:"orth"`
+	Pron    string      `xml:"pron,omitempty"`
+	Hyph    string      `xml:"hyph,omitempty"`
+}
+
+type teiGramGrp struct {
+	Grams []teiGram `xml:"gram"`
+}
+
+type teiGram struct {
+	Type  string `xml:"type,attr"`
+	Norm  string `xml:"norm,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type teiSense struct {
+	ID      string      `xml:"xml:id,attr"`
+	N       int         `xml:"n,attr,omitempty"`
+	GramGrp *teiGramGrp `xml:"gramGrp"`
+	Def     *teiDef     `xml:"def"`
+	Notes   []teiNote   `xml:"note"`
+	Cits    []teiCit    `xml:"cit"`
+	Xrs     []teiXr     `xml:"xr"`
+	Entries []teiEntry  `xml:"entry"`
+}
+
+type teiDef struct {
+	Lang  string `xml:"xml:lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+type teiNote struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type teiCit struct {
+	Type  string    `xml:"type,attr"`
+	Lang  string    `xml:"xml:lang,attr,omitempty"`
+	Quote string    `xml:"quote"`
+	Notes []teiNote `xml:"note"`
+	Cits  []teiCit  `xml:"cit"`
+}
+
+type teiXr struct {
+	Type string `xml:"type,attr"`
+	Ref  teiRef `xml:"ref"`
+}
+
+type teiRef struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// teiBuilder converts the articles of one dictionary, keeping xml:id values unique
+type teiBuilder struct {
+	dict catalog.Dictionary
+	lang string
+	ids  map[string]bool
+}
+
+// TEI writes a dictionary as a TEI Lex-0 document to path and validates the
+// result with ValidateTEI. It returns the number of entries written; the file
+// is removed again if it could not be written or fails validation.
+func TEI(path string, dict catalog.Dictionary) (int, error) {
+	count, err := writeTEIFile(path, dict)
+	if err != nil {
+		os.Remove(path)
+		return count, err
+	}
+
+	written, err := os.Open(path)
+	if err != nil {
+		return count, err
+	}
+	err = ValidateTEI(written)
+	written.Close()
+	if err != nil {
+		os.Remove(path)
+		return count, fmt.Errorf("%s is not valid TEI Lex-0: %v", path, err)
+	}
+
+	return count, nil
+}
+
+// writeTEIFile creates path and writes the document to it
+func writeTEIFile(path string, dict catalog.Dictionary) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	count, err := writeTEI(w, dict)
+	if err != nil {
+		return count, err
+	}
+	if err := w.Flush(); err != nil {
+		return count, err
+	}
+	return count, f.Close()
+}
+
+// writeTEI streams the header, one entry per lemma and the closing tags to w
+func writeTEI(w io.Writer, dict catalog.Dictionary) (int, error) {
+	b := &teiBuilder{dict: dict, lang: dict.LanguageTag(), ids: make(map[string]bool)}
+
+	source := "Converted from " + dict.File
+	if dict.Revision != "" {
+		source += ", SVN revision " + dict.Revision
+	}
+	source += ", on " + time.Now().Format("2006-01-02") + "."
+
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<TEI xmlns="%s" xml:lang="sv">
+  <teiHeader>
+    <fileDesc>
+      <titleStmt>
+        <title>Lexin svenska–%s</title>
+      </titleStmt>
+      <publicationStmt>
+        <publisher>Institutet för språk och folkminnen</publisher>
+        <ptr target="https://sprakresurser.isof.se/lexin/"/>
+      </publicationStmt>
+      <sourceDesc>
+        <p>%s</p>
+      </sourceDesc>
+    </fileDesc>
+  </teiHeader>
+  <text>
+    <body>
+`, teiNamespace, teiEscape(dict.Language), teiEscape(source))
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("      ", "  ")
+
+	count := 0
+	for article, err := range lexin.Articles(dict.Path) {
+		if err != nil {
+			return count, fmt.Errorf("%s: %v", dict.File, err)
+		}
+		for i := range article.Lemmas {
+			// An entry needs an orth, so lemmas without a headword are left out
+			if article.Lemmas[i].Value == "" {
+				continue
+			}
+			entry := b.entry(article, &article.Lemmas[i], i)
+			if err := encoder.Encode(entry); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return count, err
+	}
+
+	if count > 0 {
+		io.WriteString(w, "\n")
+	}
+	_, err := io.WriteString(w, "    </body>\n  </text>\n</TEI>\n")
+	return count, err
+}
+
+// entry maps a lemma with its senses to a main entry
+func (b *teiBuilder) entry(article *lexin.Article, lemma *lexin.Lemma, index int) teiEntry {
+	idBase := lemma.ID
+	if idBase == "" {
+		idBase = fmt.Sprintf("%s-%d", article.ID, index+1)
+	}
+	entry := teiEntry{
+		ID:   b.id(b.dict.ID() + "." + teiIDPart(idBase)),
+		Lang: "sv",
+		Type: "mainEntry",
+		Forms: []teiForm{{
+			Type: "lemma",
+			Orth: lemma.Value,
+			Pron: lemma.Pronunciation(),
+			Hyph: lemma.Hyphenate,
+		}},
+	}
+
+	for _, inflection := range lemma.Inflections {
+		if inflection.Value == "" {
+			continue
+		}
+		form := teiForm{Type: "inflected", Orth: inflection.Value}
+		if inflection.Form != "" {
+			form.GramGrp = &teiGramGrp{Grams: []teiGram{{Type: "inflection", Value: inflection.Form}}}
+		}
+		entry.Forms = append(entry.Forms, form)
+	}
+
+	if lemma.WordClass != "" {
+		entry.GramGrp = &teiGramGrp{Grams: []teiGram{{
+			Type:  "pos",
+			Norm:  teiPartOfSpeech[normalizeWordClass(lemma.WordClass)],
+			Value: lemma.WordClass,
+		}}}
+	}
+	if lemma.Comment != "" {
+		entry.Notes = append(entry.Notes, teiNote{Value: lemma.Comment})
+	}
+
+	for i := range lemma.Lexemes {
+		entry.Senses = append(entry.Senses, b.sense(entry.ID, &lemma.Lexemes[i], i+1))
+	}
+	entry.Xrs = teiReferences(lemma.References, nil)
+
+	return entry
+}
+
+// sense maps a lexeme to a sense with its translation equivalents, examples and nested entries
+func (b *teiBuilder) sense(entryID string, lexeme *lexin.Lexeme, n int) teiSense {
+	sense := teiSense{ID: b.id(fmt.Sprintf("%s.s%d", entryID, n)), N: n}
+
+	if lexeme.GramInfo != "" {
+		sense.GramGrp = &teiGramGrp{Grams: []teiGram{{Type: "construction", Value: lexeme.GramInfo}}}
+	}
+	if lexeme.Definition != "" {
+		sense.Def = &teiDef{Lang: "sv", Value: lexeme.Definition}
+	}
+	if lexeme.Explanation != "" {
+		sense.Notes = append(sense.Notes, teiNote{Type: "explanation", Value: lexeme.Explanation})
+	}
+	if lexeme.Comment != "" {
+		sense.Notes = append(sense.Notes, teiNote{Value: lexeme.Comment})
+	}
+
+	sense.Cits = b.translations("translationEquivalent", lexeme.Translations)
+	for _, example := range lexeme.Examples {
+		if example.Value == "" {
+			continue
+		}
+		sense.Cits = append(sense.Cits, teiCit{
+			Type:  "example",
+			Lang:  "sv",
+			Quote: example.Value,
+			Cits:  b.translations("translation", example.Translations),
+		})
+	}
+
+	sense.Xrs = teiReferences(lexeme.References, lexeme.Antonyms)
+
+	for i, idiom := range lexeme.Idioms {
+		if idiom.Value == "" {
+			continue
+		}
+		sense.Entries = append(sense.Entries, b.nested(sense.ID, "idiom", i+1, idiom.Value, nil, idiom.Definition, idiom.Translations))
+	}
+	for i, compound := range lexeme.Compounds {
+		if compound.Value == "" {
+			continue
+		}
+		sense.Entries = append(sense.Entries, b.nested(sense.ID, "compound", i+1, compound.Value, compound.Inflections, "", compound.Translations))
+	}
+
+	return sense
+}
+
+// nested maps an idiom or compound to an entry inside the sense it belongs to
+func (b *teiBuilder) nested(senseID, kind string, n int, value string, inflections []lexin.Inflection, definition string, translations []lexin.Translation) teiEntry {
+	id := b.id(fmt.Sprintf("%s.%s%d", senseID, kind, n))
+	entry := teiEntry{
+		ID:    id,
+		Type:  kind,
+		Forms: []teiForm{{Type: "lemma", Orth: value}},
+	}
+	for _, inflection := range inflections {
+		if inflection.Value != "" {
+			entry.Forms = append(entry.Forms, teiForm{Type: "inflected", Orth: inflection.Value})
+		}
+	}
+
+	sense := teiSense{ID: b.id(id + ".s1"), Cits: b.translations("translationEquivalent", translations)}
+	if definition != "" {
+		sense.Def = &teiDef{Lang: "sv", Value: definition}
+	}
+	entry.Senses = []teiSense{sense}
+
+	return entry
+}
+
+// translations maps target-language renderings to cit elements of the given type
+func (b *teiBuilder) translations(citType string, translations []lexin.Translation) []teiCit {
+	var cits []teiCit
+	for _, t := range translations {
+		if t.Value == "" {
+			continue
+		}
+		cit := teiCit{Type: citType, Lang: b.lang, Quote: t.Value}
+		if t.Comment != "" {
+			cit.Notes = []teiNote{{Value: t.Comment}}
+		}
+		cits = append(cits, cit)
+	}
+	return cits
+}
+
+// teiReferences maps cross-references and antonyms to xr elements
+func teiReferences(refs []lexin.Reference, antonyms []string) []teiXr {
+	var xrs []teiXr
+	for _, ref := range refs {
+		if ref.Value == "" {
+			continue
+		}
+		xrs = append(xrs, teiXr{Type: teiXrType(ref.Type), Ref: teiRef{Type: "entry", Value: ref.Value}})
+	}
+	for _, antonym := range antonyms {
+		if antonym != "" {
+			xrs = append(xrs, teiXr{Type: "antonymy", Ref: teiRef{Type: "entry", Value: antonym}})
+		}
+	}
+	return xrs
+}
+
+// teiXrType maps a Lexin reference type to one of the TEI Lex-0 xr types.
+// Lexin's "see" and "compare" references, and any it adds later, become "related".
+func teiXrType(lexinType string) string {
+	switch strings.ToLower(lexinType) {
+	case "synonym", "syn":
+		return "synonymy"
+	case "antonym", "ant":
+		return "antonymy"
+	}
+	return "related"
+}
+
+// id makes an xml:id unique within the document by adding a counter if needed
+func (b *teiBuilder) id(candidate string) string {
+	id := candidate
+	for n := 2; b.ids[id]; n++ {
+		id = fmt.Sprintf("%s-%d", candidate, n)
+	}
+	b.ids[id] = true
+	return id
+}
+
+// teiIDPart replaces the characters of a Lexin identifier that are not allowed in an xml:id
+func teiIDPart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func teiEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// teiRequired are the elements every TEI Lex-0 document must contain
+var teiRequired = []string{
+	"TEI/teiHeader/fileDesc/titleStmt/title",
+	"TEI/teiHeader/fileDesc/publicationStmt",
+	"TEI/teiHeader/fileDesc/sourceDesc",
+	"TEI/text/body",
+}
+
+// teiCitTypes are the cit types the exporter produces, with whether they need xml:lang
+var teiCitTypes = map[string]bool{
+	"translationEquivalent": true,
+	"translation":           true,
+	"example":               false,
+}
+
+// teiXrTypes are the values TEI Lex-0 allows for xr/@type
+var teiXrTypes = map[string]bool{
+	"related":    true,
+	"synonymy":   true,
+	"antonymy":   true,
+	"hypernymy":  true,
+	"hyponymy":   true,
+	"meronymy":   true,
+	"holonymy":   true,
+	"entailment": true,
+	"troponymy":  true,
+}
+
+// teiFrame is an open element while validating
+type teiFrame struct {
+	name     string
+	attrs    map[string]string
+	text     strings.Builder
+	hasLemma bool // entry: has a <form type="lemma"> with a non-empty <orth>
+	hasQuote bool // cit: has a <quote>
+	hasRef   bool // xr: has a <ref>
+}
+
+// ValidateTEI checks a document against the structure TEI Lex-0 requires of
+// the export: the TEI namespace and header, a unique xml:id on every entry and
+// sense, xml:lang on main entries and translations, a lemma form with an orth
+// in every entry, a quote in every cit and a Lex-0 type on every xr.
+func ValidateTEI(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	seen := make(map[string]bool)
+	ids := make(map[string]bool)
+	var stack []*teiFrame
+
+	fail := func(format string, args ...any) error {
+		line, _ := decoder.InputPos()
+		return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if len(stack) == 0 && (t.Name.Local != "TEI" || t.Name.Space != teiNamespace) {
+				return fail("root element must be <TEI> in the %s namespace", teiNamespace)
+			}
+
+			frame := &teiFrame{name: t.Name.Local, attrs: make(map[string]string)}
+			for _, attr := range t.Attr {
+				name := attr.Name.Local
+				if attr.Name.Space == xmlNamespace {
+					name = "xml:" + name
+				}
+				frame.attrs[name] = attr.Value
+			}
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1].name
+			}
+			stack = append(stack, frame)
+
+			path := make([]string, len(stack))
+			for i, f := range stack {
+				path[i] = f.name
+			}
+			seen[strings.Join(path, "/")] = true
+
+			switch frame.name {
+			case "entry", "sense":
+				id := frame.attrs["xml:id"]
+				if id == "" {
+					return fail("<%s> without xml:id", frame.name)
+				}
+				if ids[id] {
+					return fail("duplicate xml:id %q", id)
+				}
+				ids[id] = true
+				if frame.name == "entry" && parent == "body" && frame.attrs["xml:lang"] == "" {
+					return fail("entry %q without xml:lang", id)
+				}
+			case "cit":
+				needsLang, ok := teiCitTypes[frame.attrs["type"]]
+				if !ok {
+					return fail("<cit> with unexpected type %q", frame.attrs["type"])
+				}
+				if needsLang && frame.attrs["xml:lang"] == "" {
+					return fail("<cit type=%q> without xml:lang", frame.attrs["type"])
+				}
+			case "xr":
+				if !teiXrTypes[frame.attrs["type"]] {
+					return fail("<xr> with type %q, which is not a TEI Lex-0 xr type", frame.attrs["type"])
+				}
+			}
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+
+		case xml.EndElement:
+			frame := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			var parent *teiFrame
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			switch frame.name {
+			case "orth":
+				// orth sits in a form; a lemma form marks its entry
+				if parent != nil && parent.name == "form" && parent.attrs["type"] == "lemma" && strings.TrimSpace(frame.text.String()) != "" {
+					for i := len(stack) - 1; i >= 0; i-- {
+						if stack[i].name == "entry" {
+							stack[i].hasLemma = true
+							break
+						}
+					}
+				}
+			case "quote":
+				if parent != nil && parent.name == "cit" && strings.TrimSpace(frame.text.String()) != "" {
+					parent.hasQuote = true
+				}
+			case "ref":
+				if parent != nil && parent.name == "xr" {
+					parent.hasRef = true
+				}
+			case "entry":
+				if !frame.hasLemma {
+					return fail("entry %q has no lemma form with an orth", frame.attrs["xml:id"])
+				}
+			case "cit":
+				if !frame.hasQuote {
+					return fail("<cit type=%q> without a quote", frame.attrs["type"])
+				}
+			case "xr":
+				if !frame.hasRef {
+					return fail("<xr> without a ref")
+				}
+			}
+		}
+	}
+
+	for _, path := range teiRequired {
+		if !seen[path] {
+			return fmt.Errorf("missing required element %s", path)
+		}
+	}
+	return nil
+}
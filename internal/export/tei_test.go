@@ -0,0 +1,237 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"getlexin-xml/internal/catalog"
+)
+
+// validTEI is a small document with everything ValidateTEI checks
+const validTEI = `<?xml version="1.0" encoding="UTF-8"?>
+<TEI xmlns="http://www.tei-c.org/ns/1.0" xml:lang="sv">
+  <teiHeader>
+    <fileDesc>
+      <titleStmt>
+        <title>Lexin svenska–engelska</title>
+      </titleStmt>
+      <publicationStmt>
+        <publisher>Institutet för språk och folkminnen</publisher>
+      </publicationStmt>
+      <sourceDesc>
+        <p>Converted from swe_eng.xml.</p>
+      </sourceDesc>
+    </fileDesc>
+  </teiHeader>
+  <text>
+    <body>
+      <entry xml:id="swe_eng.1" xml:lang="sv" type="mainEntry">
+        <form type="lemma"><orth>hus</orth></form>
+        <sense xml:id="swe_eng.1.s1" n="1">
+          <cit type="translationEquivalent" xml:lang="en"><quote>house</quote></cit>
+          <cit type="example" xml:lang="sv">
+            <quote>ett stort hus</quote>
+            <cit type="translation" xml:lang="en"><quote>a big house</quote></cit>
+          </cit>
+          <entry xml:id="swe_eng.1.s1.compound1" type="compound">
+            <form type="lemma"><orth>hustak</orth></form>
+            <sense xml:id="swe_eng.1.s1.compound1.s1"/>
+          </entry>
+        </sense>
+        <xr type="related"><ref type="entry">bostad</ref></xr>
+      </entry>
+    </body>
+  </text>
+</TEI>
+`
+
+func TestValidateTEI(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string // Replaced in validTEI by new to break the document
+		new     string
+		wantErr string
+	}{
+		{name: "valid"},
+		{
+			name:    "missing header",
+			old:     validTEI[strings.Index(validTEI, "  <teiHeader>"):strings.Index(validTEI, "  <text>")],
+			new:     "",
+			wantErr: "missing required element TEI/teiHeader",
+		},
+		{
+			name:    "wrong namespace",
+			old:     `xmlns="http://www.tei-c.org/ns/1.0"`,
+			new:     `xmlns="urn:example"`,
+			wantErr: "root element must be <TEI>",
+		},
+		{
+			name:    "duplicate xml:id",
+			old:     `xml:id="swe_eng.1.s1.compound1"`,
+			new:     `xml:id="swe_eng.1"`,
+			wantErr: `duplicate xml:id "swe_eng.1"`,
+		},
+		{
+			name:    "main entry without xml:lang",
+			old:     `xml:id="swe_eng.1" xml:lang="sv"`,
+			new:     `xml:id="swe_eng.1"`,
+			wantErr: "without xml:lang",
+		},
+		{
+			name:    "cit without quote",
+			old:     `<cit type="translationEquivalent" xml:lang="en"><quote>house</quote></cit>`,
+			new:     `<cit type="translationEquivalent" xml:lang="en"><note>house</note></cit>`,
+			wantErr: `<cit type="translationEquivalent"> without a quote`,
+		},
+		{
+			name:    "translation without xml:lang",
+			old:     `<cit type="translationEquivalent" xml:lang="en">`,
+			new:     `<cit type="translationEquivalent">`,
+			wantErr: "without xml:lang",
+		},
+		{
+			name:    "entry without orth",
+			old:     `<form type="lemma"><orth>hustak</orth></form>`,
+			new:     `<form type="inflected"><orth>hustaket</orth></form>`,
+			wantErr: `entry "swe_eng.1.s1.compound1" has no lemma form with an orth`,
+		},
+		{
+			name:    "entry with empty orth",
+			old:     `<orth>hus</orth>`,
+			new:     `<orth> </orth>`,
+			wantErr: `entry "swe_eng.1" has no lemma form with an orth`,
+		},
+		{
+			name:    "xr type outside Lex-0",
+			old:     `<xr type="related">`,
+			new:     `<xr type="see">`,
+			wantErr: `<xr> with type "see"`,
+		},
+		{
+			name:    "xr without ref",
+			old:     `<ref type="entry">bostad</ref>`,
+			new:     ``,
+			wantErr: "<xr> without a ref",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := validTEI
+			if tt.old != "" {
+				if !strings.Contains(doc, tt.old) {
+					t.Fatalf("test document does not contain %q", tt.old)
+				}
+				doc = strings.Replace(doc, tt.old, tt.new, 1)
+			}
+
+			err := ValidateTEI(strings.NewReader(doc))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateTEI: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateTEI error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testLexinXML has a lemma, an idiom and a compound without a headword
+// and a reference type that TEI Lex-0 does not know
+const testLexinXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1">
+    <Lemma ID="1" Value="hus" Type="subst.">
+      <Inflection>huset</Inflection>
+      <Lexeme ID="1">
+        <Definition>byggnad att bo i</Definition>
+        <Translation>house</Translation>
+        <Idiom ID="1"></Idiom>
+        <Idiom ID="2">hus och hem<Translation>house and home</Translation></Idiom>
+        <Compound ID="1"></Compound>
+        <Compound ID="2">hustak<Translation>roof</Translation></Compound>
+        <Antonym>ute</Antonym>
+      </Lexeme>
+      <Reference Type="see" Value="bostad"/>
+      <Reference Type="antonym" Value="koja"/>
+    </Lemma>
+    <Lemma ID="2" Value="" Type="subst."/>
+  </Article>
+</Dictionary>
+`
+
+func TestTEISkipsEmptyHeadwordsAndMapsReferenceTypes(t *testing.T) {
+	dir := t.TempDir()
+	dict := catalog.Dictionary{
+		Code:     "engelska",
+		Language: "English",
+		File:     "swe_eng.xml",
+		Path:     filepath.Join(dir, "swe_eng.xml"),
+	}
+	if err := os.WriteFile(dict.Path, []byte(testLexinXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "swe_eng.tei.xml")
+	count, err := TEI(path, dict)
+	if err != nil {
+		t.Fatalf("TEI: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("wrote %d entries, want 1", count)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drop the indentation so elements can be matched on one line
+	doc := regexp.MustCompile(`>\s+<`).ReplaceAllString(string(data), "><")
+
+	for _, want := range []string{
+		`<xr type="related"><ref type="entry">bostad</ref></xr>`,
+		`<xr type="antonymy"><ref type="entry">koja</ref></xr>`,
+		`<xr type="antonymy"><ref type="entry">ute</ref></xr>`,
+		`<orth>hus och hem</orth>`,
+		`<orth>hustak</orth>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document does not contain %s", want)
+		}
+	}
+	if strings.Contains(doc, `type="see"`) {
+		t.Error(`document contains the Lexin reference type "see"`)
+	}
+	if n := strings.Count(doc, `type="idiom"`); n != 1 {
+		t.Errorf("document has %d idiom entries, want 1", n)
+	}
+	if n := strings.Count(doc, `type="compound"`); n != 1 {
+		t.Errorf("document has %d compound entries, want 1", n)
+	}
+}
+
+func TestTEIRemovesFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	dict := catalog.Dictionary{
+		Code: "engelska",
+		File: "swe_eng.xml",
+		Path: filepath.Join(dir, "swe_eng.xml"),
+	}
+	if err := os.WriteFile(dict.Path, []byte(`<Dictionary><Article ID="1"><Lemma Value="hus">`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "swe_eng.tei.xml")
+	if _, err := TEI(path, dict); err == nil {
+		t.Fatal("TEI succeeded on a truncated dictionary")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("output file left behind: %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package fetcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,30 +12,56 @@ import (
 	"sync"
 	"time"
 
+	"getlexin-xml/internal/manifest"
 	"getlexin-xml/internal/models"
 	"getlexin-xml/internal/parser"
+	"getlexin-xml/internal/ratelimit"
+	"getlexin-xml/internal/retry"
+	"getlexin-xml/internal/source"
 )
 
+// partSuffix marks files that are still being downloaded
+const partSuffix = manifest.PartSuffix
+
 // DownloadManager handles concurrent downloads
 type DownloadManager struct {
-	Concurrency int
-	OutputDir   string
-	Results     chan models.DownloadResult
+	Concurrency     int // Languages downloaded at the same time
+	FileConcurrency int // Files of a language downloaded at the same time
+	OutputDir       string
+	Source          source.Source      // Where directories and files are read from
+	Limiter         *ratelimit.Limiter // Shared request and bandwidth limits for the files (optional)
+	Retry           retry.Policy
+	Sync            bool       // Skip languages whose SVN revision has not changed
+	State           *SyncState // Loaded from the output directory when nil
+	Results         chan models.DownloadResult
+
+	// Progress receives byte-level progress events (optional).
+	// It is called from the download goroutines and must not block.
+	Progress func(models.ProgressEvent)
+
+	mu        sync.Mutex
+	cancels   map[string]context.CancelFunc // Running languages by code
+	cancelled map[string]bool               // Languages cancelled by Cancel
 }
 
-// NewDownloadManager creates a new download manager
-func NewDownloadManager(concurrency int, outputDir string) *DownloadManager {
+// NewDownloadManager creates a download manager that reads from src
+func NewDownloadManager(concurrency int, outputDir string, src source.Source) *DownloadManager {
 	return &DownloadManager{
-		Concurrency: concurrency,
-		OutputDir:   outputDir,
-		Results:     make(chan models.DownloadResult),
+		Concurrency:     concurrency,
+		FileConcurrency: 1,
+		OutputDir:       outputDir,
+		Source:          src,
+		Retry:           retry.DefaultPolicy(),
+		Results:         make(chan models.DownloadResult),
 	}
 }
 
-// StartDownloads begins downloading the selected directories concurrently
-func (dm *DownloadManager) StartDownloads(directories []models.Directory) {
+// StartDownloads begins downloading the selected directories concurrently.
+// It returns immediately; results are delivered on the Results channel, which is
+// closed once every directory has been reported. Cancelling ctx stops all downloads.
+func (dm *DownloadManager) StartDownloads(ctx context.Context, directories []models.Directory) {
 	var wg sync.WaitGroup
-	sem := make(chan struct{}, dm.Concurrency)
+	sem := make(chan struct{}, max(dm.Concurrency, 1))
 
 	// Create output directory if it doesn't exist
 	err := os.MkdirAll(dm.OutputDir, 0755)
@@ -43,34 +71,100 @@ func (dm *DownloadManager) StartDownloads(directories []models.Directory) {
 		return
 	}
 
-	// Start a goroutine to close the results channel once all downloads are done
+	// Load the revisions recorded by earlier runs
+	if dm.State == nil {
+		dm.State, err = LoadSyncState(filepath.Join(dm.OutputDir, StateFileName))
+		if err != nil {
+			log.Printf("Failed to load sync state: %v", err)
+			close(dm.Results)
+			return
+		}
+	}
+
+	// Start download goroutines in the background so the caller can consume results,
+	// then close the results channel once all downloads are done
 	go func() {
+		for _, dir := range directories {
+			// Acquire semaphore, or report the directory as cancelled
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				dm.Results <- models.DownloadResult{
+					Directory: dir,
+					Error:     fmt.Errorf("download cancelled: %v", ctx.Err()),
+				}
+				continue
+			}
+
+			wg.Add(1)
+			go func(dir models.Directory) {
+				defer wg.Done()
+				defer func() { <-sem }() // Release semaphore
+
+				langCtx, cancel := dm.languageContext(ctx, dir.Code)
+				defer cancel()
+
+				result := dm.downloadDirectory(langCtx, dir)
+				dm.Results <- result
+			}(dir)
+		}
+
 		wg.Wait()
 		close(dm.Results)
 	}()
+}
 
-	// Start download goroutines
-	for _, dir := range directories {
-		wg.Add(1)
-		sem <- struct{}{} // Acquire semaphore
+// Cancel stops the download of a single language, whether it is running or still queued
+func (dm *DownloadManager) Cancel(code string) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 
-		go func(dir models.Directory) {
-			defer wg.Done()
-			defer func() { <-sem }() // Release semaphore
+	if dm.cancelled == nil {
+		dm.cancelled = make(map[string]bool)
+	}
+	dm.cancelled[code] = true
 
-			result := dm.downloadDirectory(dir)
-			dm.Results <- result
-		}(dir)
+	if cancel, ok := dm.cancels[code]; ok {
+		cancel()
+	}
+}
+
+// languageContext derives the context for one language so it can be cancelled on its own
+func (dm *DownloadManager) languageContext(ctx context.Context, code string) (context.Context, context.CancelFunc) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	langCtx, cancel := context.WithCancel(ctx)
+	if dm.cancelled[code] {
+		cancel()
+	}
+
+	if dm.cancels == nil {
+		dm.cancels = make(map[string]context.CancelFunc)
+	}
+	dm.cancels[code] = cancel
+
+	return langCtx, func() {
+		dm.mu.Lock()
+		delete(dm.cancels, code)
+		dm.mu.Unlock()
+		cancel()
 	}
 }
 
 // downloadDirectory downloads all XML files from a language directory
-func (dm *DownloadManager) downloadDirectory(dir models.Directory) models.DownloadResult {
+func (dm *DownloadManager) downloadDirectory(ctx context.Context, dir models.Directory) models.DownloadResult {
 	result := models.DownloadResult{
 		Directory: dir,
 		Success:   false,
 	}
 
+	// Skip languages cancelled while they were queued
+	if ctx.Err() != nil {
+		result.Error = fmt.Errorf("download cancelled: %v", ctx.Err())
+		return result
+	}
+
 	// Create directory-specific output folder
 	dirPath := filepath.Join(dm.OutputDir, dir.Code)
 	err := os.MkdirAll(dirPath, 0755)
@@ -79,49 +173,101 @@ func (dm *DownloadManager) downloadDirectory(dir models.Directory) models.Downlo
 		return result
 	}
 
-	// Fetch the directory page
-	resp, err := http.Get(dir.URL)
+	// List the XML files of the directory; an HTTP source applies the request limit itself
+	listing, err := dm.Source.Files(ctx, dir)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to fetch directory: %v", err)
+		result.Error = err
 		return result
 	}
-	defer resp.Body.Close()
+	xmlFiles := listing.Files
+	result.Revision = listing.Revision
 
-	// Read the directory index content
-	indexContent, err := io.ReadAll(resp.Body)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to read directory page: %v", err)
+	// In sync mode, leave the language alone if nothing moved upstream
+	if dm.Sync && dm.isUpToDate(dir.Code, dirPath, listing.Revision) {
+		result.Success = true
+		result.UpToDate = true
 		return result
 	}
 
 	// Save the directory index
-	err = os.WriteFile(filepath.Join(dirPath, "index.html"), indexContent, 0644)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to save index file: %v", err)
-		return result
+	if listing.Index != nil {
+		err = os.WriteFile(filepath.Join(dirPath, "index.html"), listing.Index, 0644)
+		if err != nil {
+			result.Error = fmt.Errorf("failed to save index file: %v", err)
+			return result
+		}
 	}
 
-	// Parse the directory contents to find XML files
-	xmlFiles, err := parser.ParseDirectoryContents(string(indexContent))
-	if err != nil {
-		result.Error = fmt.Errorf("failed to parse directory contents: %v", err)
-		return result
+	// Download the XML files, up to FileConcurrency at a time
+	fileResults := make([]*models.FileResult, len(xmlFiles))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(dm.FileConcurrency, 1))
+	for i, file := range xmlFiles {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			filePath := filepath.Join(dirPath, file.Name)
+			event := models.ProgressEvent{
+				Directory: dir.Code,
+				File:      file.Name,
+				FileIndex: i + 1,
+				FileCount: len(xmlFiles),
+			}
+
+			fileResult := dm.downloadFile(ctx, dir, file, filePath, event)
+			fileResults[i] = &fileResult
+		}()
 	}
+	wg.Wait()
 
-	// Download each XML file
-	var totalBytes int64
-	for _, file := range xmlFiles {
-		fileURL := dir.URL + file.Href
-		filePath := filepath.Join(dirPath, file.Name)
+	// Write the validators of the directory's files in one go, even if it was cancelled
+	if err := dm.State.Save(); err != nil {
+		log.Printf("Failed to save sync state: %v", err)
+	}
 
-		bytes, err := dm.downloadFile(filePath, fileURL)
-		if err != nil {
-			log.Printf("Error downloading %s: %v", file.Name, err)
-		} else {
+	// Tally the files in listing order; files never started because of cancellation are left out
+	var totalBytes int64
+	failedFiles := 0
+	for _, fileResult := range fileResults {
+		if fileResult == nil {
+			continue
+		}
+		result.Files = append(result.Files, *fileResult)
+		switch {
+		case fileResult.Error != nil:
+			failedFiles++
+		case fileResult.NotModified:
+			result.NotModifiedCount++
+			totalBytes += fileResult.Bytes
+		default:
 			result.FileCount++
-			totalBytes += bytes
+			totalBytes += fileResult.Bytes
 		}
 	}
+	result.TotalBytes = totalBytes
+
+	// Leave the directory as it is when cancelled; the next run picks up from here
+	if ctx.Err() != nil {
+		result.Error = fmt.Errorf("download cancelled: %v", ctx.Err())
+		return result
+	}
+
+	// Write the checksum manifest for the files on disk
+	err = dm.writeManifest(dirPath, dir, listing.Revision, result.Files)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to write manifest: %v", err)
+		return result
+	}
 
 	// Create metadata file
 	metaFile, err := os.Create(filepath.Join(dirPath, "metadata.txt"))
@@ -132,44 +278,303 @@ func (dm *DownloadManager) downloadDirectory(dir models.Directory) models.Downlo
 	defer metaFile.Close()
 
 	// Write metadata
-	_, err = fmt.Fprintf(metaFile, "Code: %s\nName: %s\nURL: %s\nDownloaded: %s\nFiles: %d\nTotal Size: %d bytes\n",
-		dir.Code, dir.Name, dir.URL, time.Now().Format(time.RFC3339), result.FileCount, totalBytes)
+	_, err = fmt.Fprintf(metaFile, "Code: %s\nName: %s\nURL: %s\nRevision: %s\nDownloaded: %s\nFiles: %d\nTotal Size: %d bytes\n",
+		dir.Code, dir.Name, dir.URL, listing.Revision, time.Now().Format(time.RFC3339), result.FileCount+result.NotModifiedCount, totalBytes)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to write metadata: %v", err)
 		return result
 	}
 
+	// Report missing files without recording the revision, so the next sync tries again
+	if failedFiles > 0 {
+		result.Partial = true
+		result.Error = fmt.Errorf("%d of %d files failed", failedFiles, len(xmlFiles))
+		return result
+	}
+
+	// Remember the revision so the next sync can skip this language
+	err = dm.State.Record(dir.Code, listing.Revision)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to record sync state: %v", err)
+		return result
+	}
+
 	result.Success = true
-	result.TotalBytes = totalBytes
 	return result
 }
 
-// downloadFile downloads a file from a URL to a local path and returns the number of bytes downloaded
-func (dm *DownloadManager) downloadFile(filepath string, url string) (int64, error) {
-	// Create the file
-	out, err := os.Create(filepath)
+// writeManifest hashes the downloaded files and writes the language manifest.
+// Unchanged files keep the fetch time recorded by the previous manifest, and files that
+// failed this time keep their previous entry as long as the old copy is still intact.
+func (dm *DownloadManager) writeManifest(dirPath string, dir models.Directory, revision string, files []models.FileResult) error {
+	previous, _ := manifest.Load(dirPath)
+	now := time.Now().UTC()
+
+	m := &manifest.Manifest{
+		Code:        dir.Code,
+		Name:        dir.Name,
+		SourceURL:   dir.URL,
+		Revision:    revision,
+		GeneratedAt: now,
+	}
+
+	for _, file := range files {
+		size, sum, err := manifest.HashFile(filepath.Join(dirPath, file.Name))
+		if file.Error != nil {
+			if previous == nil || err != nil {
+				continue
+			}
+			if entry, ok := previous.File(file.Name); ok && entry.SHA256 == sum {
+				m.Files = append(m.Files, entry)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		fetchedAt := now
+		if file.NotModified && previous != nil {
+			if entry, ok := previous.File(file.Name); ok && entry.SHA256 == sum {
+				fetchedAt = entry.FetchedAt
+			}
+		}
+
+		m.Files = append(m.Files, manifest.FileEntry{
+			Name:      file.Name,
+			Size:      size,
+			SHA256:    sum,
+			SourceURL: file.URL,
+			Revision:  revision,
+			FetchedAt: fetchedAt,
+		})
+	}
+
+	return m.Write(dirPath)
+}
+
+// isUpToDate reports whether a language was already synced at the given revision and is still on disk
+func (dm *DownloadManager) isUpToDate(code, dirPath, revision string) bool {
+	if revision == "" || dm.State.Revision(code) != revision {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(dirPath, "metadata.txt"))
+	return err == nil
+}
+
+// downloadFile downloads a file of a directory to a local path and reports the outcome.
+// Failed attempts are retried according to the retry policy, resuming from the partial file.
+// Progress events are based on event, which identifies the file within its directory.
+func (dm *DownloadManager) downloadFile(ctx context.Context, dir models.Directory, file models.File, destPath string, event models.ProgressEvent) models.FileResult {
+	start := time.Now()
+	result := models.FileResult{
+		Name: filepath.Base(destPath),
+		URL:  dir.URL + file.Href,
+	}
+
+	result.Error = dm.Retry.Do(ctx, func() error {
+		return dm.fetchFile(ctx, dir, file, destPath, &result, event)
+	})
+	result.Duration = time.Since(start)
+
+	// Final event for the file
+	if dm.Progress != nil {
+		event.BytesRead = result.Bytes
+		event.ContentLength = result.Bytes
+		if result.Duration > 0 && !result.NotModified {
+			event.Throughput = float64(result.Bytes) / result.Duration.Seconds()
+		}
+		event.Done = true
+		event.Error = result.Error
+		dm.Progress(event)
+	}
+
+	return result
+}
+
+// fetchFile makes a single attempt at downloading a file.
+// Data is written to a ".part" file next to the target, which is resumed from its current size
+// when the source supports it and renamed into place only once the download is complete.
+// A resume is guarded with If-Range, so a file that changed upstream in the meantime is sent whole.
+// An existing complete file is revalidated with the ETag/Last-Modified recorded for it.
+// The partial file is removed if ctx is cancelled mid-transfer; one left by a failed
+// transfer or a killed process is resumed.
+func (dm *DownloadManager) fetchFile(ctx context.Context, dir models.Directory, file models.File, destPath string, result *models.FileResult, event models.ProgressEvent) error {
+	partPath := destPath + partSuffix
+	key := dm.stateKey(destPath)
+
+	// Pick up where a previous attempt left off
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	// Resume a partial file as long as the remote file has not changed since it was
+	// started, or ask whether the complete local copy is still current
+	req := source.Request{Offset: offset}
+	var localSize int64 = -1
+	if offset > 0 && dm.State != nil {
+		req.IfRange = dm.State.File(key + partSuffix).ifRange()
+	}
+	if offset == 0 {
+		if info, err := os.Stat(destPath); err == nil && dm.State != nil {
+			validators := dm.State.File(key)
+			req.ETag = validators.ETag
+			req.LastModified = validators.LastModified
+			localSize = info.Size()
+		}
+	}
+
+	// Get the data, once the rate limit allows another request
+	if err := dm.Limiter.WaitRequest(ctx); err != nil {
+		return err
+	}
+	src, err := dm.Source.Open(ctx, dir, file, req)
 	if err != nil {
-		return 0, err
+		var statusErr *retry.StatusError
+		if errors.As(err, &statusErr) {
+			result.HTTPStatus = statusErr.StatusCode
+			if statusErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+				// The partial file is not a prefix of the remote file; discard it so the next attempt starts fresh
+				os.Remove(partPath)
+				dm.recordPart(key, FileState{})
+				return fmt.Errorf("bad status: %s", statusErr.Status)
+			}
+		}
+		return err
 	}
-	defer out.Close()
+	defer src.Close()
+	result.HTTPStatus = src.Status
 
-	// Get the data
-	resp, err := http.Get(url)
+	// Decide whether to keep the local copy, append or start over
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case src.NotModified:
+		if localSize < 0 {
+			return retry.Permanent(fmt.Errorf("unexpected not-modified response without a local copy"))
+		}
+		result.Bytes = localSize
+		result.NotModified = true
+		return nil
+	case src.Offset == 0:
+		// The source ignored the offset, the file changed since the partial file was
+		// started, or there was nothing to resume
+		offset = 0
+		flags |= os.O_TRUNC
+		dm.recordPart(key, FileState{ETag: src.ETag, LastModified: src.LastModified})
+	case src.Offset == offset:
+		flags |= os.O_APPEND
+	default:
+		os.Remove(partPath)
+		dm.recordPart(key, FileState{})
+		return fmt.Errorf("source resumed at byte %d instead of %d", src.Offset, offset)
+	}
+
+	// Open the partial file
+	out, err := os.OpenFile(partPath, flags, 0644)
 	if err != nil {
-		return 0, err
+		return retry.Permanent(err)
 	}
-	defer resp.Body.Close()
 
-	// Check server response
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("bad status: %s", resp.Status)
+	// Pace the transfer to the bandwidth limit and report progress if anyone is listening
+	body := dm.Limiter.Reader(ctx, src)
+	if dm.Progress != nil {
+		event.BytesRead = offset
+		event.ContentLength = -1
+		if src.Size >= 0 {
+			event.ContentLength = offset + src.Size
+		}
+		body = newProgressReader(body, dm.Progress, event)
 	}
 
 	// Write the body to file
-	bytesWritten, err := io.Copy(out, resp.Body)
+	bytesWritten, err := io.Copy(out, body)
+	if err != nil {
+		out.Close()
+		if ctx.Err() != nil {
+			// Clean up after a cancelled transfer rather than leave a partial file behind
+			os.Remove(partPath)
+			dm.recordPart(key, FileState{})
+			return ctx.Err()
+		}
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// Make sure the source sent a complete XML document and not an error page
+	if err := validateXMLFile(partPath); err != nil {
+		dm.recordPart(key, FileState{})
+		quarantinePath, qerr := quarantine(partPath, destPath)
+		if qerr != nil {
+			os.Remove(partPath)
+			return retry.Permanent(fmt.Errorf("invalid XML (%v), quarantine failed: %v", err, qerr))
+		}
+		result.QuarantinePath = quarantinePath
+		// The source would most likely send the same payload again, so do not retry
+		return retry.Permanent(fmt.Errorf("invalid XML, quarantined as %s: %v", quarantinePath, err))
+	}
+
+	// Move the completed file into place
+	if err := os.Rename(partPath, destPath); err != nil {
+		return retry.Permanent(err)
+	}
+
+	// Remember the validators for the next conditional request
+	dm.recordPart(key, FileState{})
+	if dm.State != nil {
+		dm.State.RecordFile(key, FileState{
+			ETag:         src.ETag,
+			LastModified: src.LastModified,
+		})
+	}
+
+	result.Bytes = offset + bytesWritten
+	return nil
+}
+
+// validateXMLFile checks that a downloaded file is well-formed XML
+func validateXMLFile(path string) error {
+	f, err := os.Open(path)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	defer f.Close()
+
+	return parser.ValidateXML(f)
+}
+
+// quarantine moves an invalid download out of the language folder into its quarantine subfolder
+func quarantine(partPath, destPath string) (string, error) {
+	dir := filepath.Join(filepath.Dir(destPath), manifest.QuarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	quarantinePath := filepath.Join(dir, filepath.Base(destPath))
+	if err := os.Rename(partPath, quarantinePath); err != nil {
+		return "", err
+	}
+
+	return quarantinePath, nil
+}
+
+// recordPart remembers the validators of the response a partial file was started
+// with, so a later resume can send them as If-Range; an empty state forgets them
+func (dm *DownloadManager) recordPart(key string, file FileState) {
+	if dm.State != nil {
+		dm.State.RecordFile(key+partSuffix, file)
 	}
+}
 
-	return bytesWritten, nil
+// stateKey returns the sync state key ("<code>/<file name>") for a local file
+func (dm *DownloadManager) stateKey(destPath string) string {
+	rel, err := filepath.Rel(dm.OutputDir, destPath)
+	if err != nil {
+		return filepath.ToSlash(destPath)
+	}
+	return filepath.ToSlash(rel)
 }
@@ -0,0 +1,359 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"getlexin-xml/internal/manifest"
+	"getlexin-xml/internal/models"
+	"getlexin-xml/internal/retry"
+	"getlexin-xml/internal/source"
+)
+
+// testXML is the remote file served by the test servers
+var testXML = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1"><Lemma Value="hus"/></Article>
+  <Article ID="2"><Lemma Value="bil"/></Article>
+  <Article ID="3"><Lemma Value="katt"/></Article>
+</Dictionary>
+`)
+
+// newTestManager returns a download manager reading from the server at url,
+// along with the directory and file the tests download
+func newTestManager(t *testing.T, url string, client *http.Client) (*DownloadManager, models.Directory, models.File) {
+	t.Helper()
+
+	dm := &DownloadManager{
+		OutputDir: t.TempDir(),
+		Source:    source.NewHTTP(url, client),
+		Retry:     retry.Policy{MaxAttempts: 1},
+	}
+	dir := models.Directory{Code: "svenska", URL: url + "/svenska/"}
+	file := models.File{Name: "swe.xml", Href: "swe.xml"}
+
+	if err := os.MkdirAll(filepath.Join(dm.OutputDir, dir.Code), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dm, dir, file
+}
+
+// serveTestXML serves testXML with support for Range requests
+func serveTestXML(w http.ResponseWriter, r *http.Request) {
+	http.ServeContent(w, r, "swe.xml", time.Time{}, bytes.NewReader(testXML))
+}
+
+func TestDownloadFileResumesAfterInterruption(t *testing.T) {
+	half := len(testXML) / 2
+
+	var mu sync.Mutex
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		if first {
+			// Promise the whole file but drop the connection halfway through
+			w.Header().Set("Content-Length", fmt.Sprint(len(testXML)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(testXML[:half])
+			return
+		}
+		serveTestXML(w, r)
+	}))
+	defer srv.Close()
+
+	dm, dir, file := newTestManager(t, srv.URL, srv.Client())
+	dm.Retry = retry.Policy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	destPath := filepath.Join(dm.OutputDir, dir.Code, file.Name)
+
+	result := dm.downloadFile(context.Background(), dir, file, destPath, models.ProgressEvent{})
+	if result.Error != nil {
+		t.Fatalf("downloadFile: %v", result.Error)
+	}
+
+	if len(ranges) != 2 {
+		t.Fatalf("got %d requests, want 2", len(ranges))
+	}
+	if want := fmt.Sprintf("bytes=%d-", half); ranges[1] != want {
+		t.Errorf("second request Range = %q, want %q", ranges[1], want)
+	}
+	if result.HTTPStatus != http.StatusPartialContent {
+		t.Errorf("HTTPStatus = %d, want %d", result.HTTPStatus, http.StatusPartialContent)
+	}
+	if result.Bytes != int64(len(testXML)) {
+		t.Errorf("Bytes = %d, want %d", result.Bytes, len(testXML))
+	}
+
+	got, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, testXML) {
+		t.Errorf("downloaded file does not match the remote file:\n%s", got)
+	}
+	if _, err := os.Stat(destPath + partSuffix); !os.IsNotExist(err) {
+		t.Errorf("partial file left behind: %v", err)
+	}
+}
+
+func TestDownloadFileRestartsWhenFileChangedBetweenRuns(t *testing.T) {
+	changed := bytes.Replace(testXML, []byte("hus"), []byte("hem"), 1)
+	half := len(testXML) / 2
+
+	var mu sync.Mutex
+	var ifRange []string
+	current, etag := testXML, `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		ifRange = append(ifRange, r.Header.Get("If-Range"))
+
+		w.Header().Set("ETag", etag)
+		if len(ifRange) == 1 {
+			// Drop the connection halfway through the first version
+			w.Header().Set("Content-Length", fmt.Sprint(len(current)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(current[:half])
+			return
+		}
+		http.ServeContent(w, r, "swe.xml", time.Time{}, bytes.NewReader(current))
+	}))
+	defer srv.Close()
+
+	dm, dir, file := newTestManager(t, srv.URL, srv.Client())
+	state, err := LoadSyncState(filepath.Join(dm.OutputDir, StateFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm.State = state
+	destPath := filepath.Join(dm.OutputDir, dir.Code, file.Name)
+
+	// The first run is interrupted and leaves a partial file of the first version
+	if result := dm.downloadFile(context.Background(), dir, file, destPath, models.ProgressEvent{}); result.Error == nil {
+		t.Fatal("interrupted download succeeded")
+	}
+	if info, err := os.Stat(destPath + partSuffix); err != nil || info.Size() != int64(half) {
+		t.Fatalf("partial file after the first run: %v, %v", info, err)
+	}
+
+	// The file changes upstream before the next run
+	mu.Lock()
+	current, etag = changed, `"v2"`
+	mu.Unlock()
+
+	result := dm.downloadFile(context.Background(), dir, file, destPath, models.ProgressEvent{})
+	if result.Error != nil {
+		t.Fatalf("downloadFile: %v", result.Error)
+	}
+
+	if ifRange[1] != `"v1"` {
+		t.Errorf("If-Range = %q, want the ETag the partial file was started with", ifRange[1])
+	}
+	if result.HTTPStatus != http.StatusOK {
+		t.Errorf("HTTPStatus = %d, want %d", result.HTTPStatus, http.StatusOK)
+	}
+	got, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, changed) {
+		t.Errorf("downloaded file is not the new version:\n%s", got)
+	}
+
+	// The validators of the partial file are forgotten, those of the complete file recorded
+	if part := state.File("svenska/swe.xml" + partSuffix); part != (FileState{}) {
+		t.Errorf("validators of the partial file kept: %+v", part)
+	}
+	if complete := state.File("svenska/swe.xml"); complete.ETag != `"v2"` {
+		t.Errorf("ETag of the complete file = %q, want %q", complete.ETag, `"v2"`)
+	}
+}
+
+func TestFetchFileRange(t *testing.T) {
+	prefix := testXML[:40]
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name:    "partial content is appended",
+			handler: serveTestXML,
+		},
+		{
+			name: "full content replaces the partial file",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write(testXML)
+			},
+		},
+		{
+			name: "range not satisfiable drops the partial file",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(testXML)))
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			},
+			wantErr: "416",
+		},
+		{
+			name: "mismatched start is rejected",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				start := len(prefix) + 5
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(testXML)-1, len(testXML)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(testXML[start:])
+			},
+			wantErr: fmt.Sprintf("instead of %d", len(prefix)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRange string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRange = r.Header.Get("Range")
+				tt.handler(w, r)
+			}))
+			defer srv.Close()
+
+			dm, dir, file := newTestManager(t, srv.URL, srv.Client())
+			destPath := filepath.Join(dm.OutputDir, dir.Code, file.Name)
+			partPath := destPath + partSuffix
+
+			// Leave the first bytes of the file behind, as an interrupted run would
+			if err := os.WriteFile(partPath, prefix, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var result models.FileResult
+			err := dm.fetchFile(context.Background(), dir, file, destPath, &result, models.ProgressEvent{})
+
+			if want := fmt.Sprintf("bytes=%d-", len(prefix)); gotRange != want {
+				t.Errorf("Range = %q, want %q", gotRange, want)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("fetchFile error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+					t.Errorf("partial file kept after failure: %v", err)
+				}
+				if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+					t.Errorf("file moved into place after failure: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fetchFile: %v", err)
+			}
+			got, err := os.ReadFile(destPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, testXML) {
+				t.Errorf("downloaded file does not match the remote file:\n%s", got)
+			}
+			if result.Bytes != int64(len(testXML)) {
+				t.Errorf("Bytes = %d, want %d", result.Bytes, len(testXML))
+			}
+		})
+	}
+}
+
+func TestFetchFileRemovesPartialFileOnCancel(t *testing.T) {
+	half := len(testXML) / 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Send half of the file, then stall until the download is cancelled
+		w.Header().Set("Content-Length", fmt.Sprint(len(testXML)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(testXML[:half])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	dm, dir, file := newTestManager(t, srv.URL, srv.Client())
+	destPath := filepath.Join(dm.OutputDir, dir.Code, file.Name)
+	partPath := destPath + partSuffix
+
+	done := make(chan error)
+	go func() {
+		var result models.FileResult
+		done <- dm.fetchFile(ctx, dir, file, destPath, &result, models.ProgressEvent{})
+	}()
+
+	// Cancel once the first half has reached the partial file
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if info, err := os.Stat(partPath); err == nil && info.Size() == int64(half) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first half of the file never reached the partial file")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("fetchFile error = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+		t.Errorf("partial file kept after cancel: %v", err)
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("file moved into place after cancel: %v", err)
+	}
+}
+
+func TestDownloadFileQuarantinesInvalidXMLWithoutRetrying(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><body><p>Service temporarily unavailable<br></body></html>")
+	}))
+	defer srv.Close()
+
+	dm, dir, file := newTestManager(t, srv.URL, srv.Client())
+	dm.Retry = retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	destPath := filepath.Join(dm.OutputDir, dir.Code, file.Name)
+
+	result := dm.downloadFile(context.Background(), dir, file, destPath, models.ProgressEvent{})
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "invalid XML") {
+		t.Fatalf("downloadFile error = %v, want an invalid XML error", result.Error)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+
+	wantQuarantine := filepath.Join(dm.OutputDir, dir.Code, manifest.QuarantineDir, file.Name)
+	if result.QuarantinePath != wantQuarantine {
+		t.Errorf("QuarantinePath = %q, want %q", result.QuarantinePath, wantQuarantine)
+	}
+	if _, err := os.Stat(wantQuarantine); err != nil {
+		t.Errorf("quarantined file missing: %v", err)
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("invalid file moved into place: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package fetcher
+
+import (
+	"io"
+	"time"
+
+	"getlexin-xml/internal/models"
+)
+
+// progressInterval limits how often progress events are emitted for a single file
+const progressInterval = 100 * time.Millisecond
+
+// progressReader reports the bytes read through it as progress events
+type progressReader struct {
+	r           io.Reader
+	report      func(models.ProgressEvent)
+	event       models.ProgressEvent
+	start       time.Time
+	lastEmit    time.Time
+	transferred int64
+}
+
+// newProgressReader wraps r; event carries the file details and the bytes already on disk
+func newProgressReader(r io.Reader, report func(models.ProgressEvent), event models.ProgressEvent) *progressReader {
+	now := time.Now()
+	pr := &progressReader{
+		r:        r,
+		report:   report,
+		event:    event,
+		start:    now,
+		lastEmit: now,
+	}
+	pr.emit()
+	return pr
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.transferred += int64(n)
+	pr.event.BytesRead += int64(n)
+
+	if time.Since(pr.lastEmit) >= progressInterval || err == io.EOF {
+		pr.emit()
+	}
+
+	return n, err
+}
+
+// emit sends the current state along with the throughput of this transfer
+func (pr *progressReader) emit() {
+	pr.lastEmit = time.Now()
+	if elapsed := pr.lastEmit.Sub(pr.start).Seconds(); elapsed > 0 {
+		pr.event.Throughput = float64(pr.transferred) / elapsed
+	}
+	pr.report(pr.event)
+}
@@ -0,0 +1,159 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateFileName is the name of the sync state file in the output directory
+const StateFileName = "sync-state.json"
+
+// LanguageState records what was last synced for a language directory
+type LanguageState struct {
+	Revision string    `json:"revision"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// FileState holds the HTTP validators of a downloaded file for conditional requests
+type FileState struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ifRange returns the validator to send as If-Range: the ETag unless it is weak,
+// which If-Range does not allow, and otherwise Last-Modified
+func (f FileState) ifRange() string {
+	if f.ETag != "" && !strings.HasPrefix(f.ETag, "W/") {
+		return f.ETag
+	}
+	return f.LastModified
+}
+
+// SyncState tracks the SVN revision of every downloaded language directory
+// and the validators of every downloaded file
+type SyncState struct {
+	mu        sync.Mutex
+	path      string
+	dirty     bool                     // Recorded changes not written yet
+	Languages map[string]LanguageState `json:"languages"`
+	Files     map[string]FileState     `json:"files"` // Keyed by "<code>/<file name>", with ".part" for partial files
+}
+
+// LoadSyncState reads the state file at path, returning an empty state if it does not exist
+func LoadSyncState(path string) (*SyncState, error) {
+	state := &SyncState{
+		path:      path,
+		Languages: make(map[string]LanguageState),
+		Files:     make(map[string]FileState),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %v", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state %s: %v", path, err)
+	}
+	if state.Languages == nil {
+		state.Languages = make(map[string]LanguageState)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]FileState)
+	}
+
+	return state, nil
+}
+
+// Revision returns the recorded revision for a language code
+func (s *SyncState) Revision(code string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Languages[code].Revision
+}
+
+// Record stores the revision for a language code and writes the state file,
+// along with the file validators recorded since the last write
+func (s *SyncState) Record(code, revision string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Languages[code] = LanguageState{
+		Revision: revision,
+		SyncedAt: time.Now().UTC(),
+	}
+
+	return s.save()
+}
+
+// File returns the recorded validators for a file key
+func (s *SyncState) File(key string) FileState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Files[key]
+}
+
+// RecordFile stores the validators for a file key in memory; they are written
+// by the next Save or Record. Files served without any validator are forgotten.
+func (s *SyncState) RecordFile(key string, file FileState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if file.ETag == "" && file.LastModified == "" {
+		if _, ok := s.Files[key]; !ok {
+			return
+		}
+		delete(s.Files, key)
+	} else {
+		s.Files[key] = file
+	}
+	s.dirty = true
+}
+
+// Save writes the state file if anything was recorded since it was last written
+func (s *SyncState) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+// save writes the state file atomically; the caller must hold the lock
+func (s *SyncState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write sync state: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write sync state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write sync state: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
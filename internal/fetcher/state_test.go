@@ -0,0 +1,60 @@
+package fetcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncStateWritesFilesOnSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFileName)
+	state, err := LoadSyncState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Recording validators stays in memory
+	state.RecordFile("svenska/swe.xml", FileState{ETag: `"v1"`})
+	state.RecordFile("svenska/swe_2.xml", FileState{LastModified: "Fri, 17 May 2024 10:30:00 GMT"})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("state file written before Save: %v", err)
+	}
+
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := LoadSyncState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.File("svenska/swe.xml"); got.ETag != `"v1"` {
+		t.Errorf("ETag after reload = %q, want %q", got.ETag, `"v1"`)
+	}
+	if len(loaded.Files) != 2 {
+		t.Errorf("got %d files after reload, want 2", len(loaded.Files))
+	}
+
+	// Nothing new to write leaves the file alone
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("state file rewritten without changes: %v", err)
+	}
+
+	// Forgetting a file is a change too
+	state.RecordFile("svenska/swe.xml", FileState{})
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err = LoadSyncState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loaded.Files["svenska/swe.xml"]; ok {
+		t.Error("forgotten file still in the state file")
+	}
+}
@@ -0,0 +1,173 @@
+// Package httpclient builds the HTTP client shared by every request of a download run.
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultUserAgent identifies the downloader to the servers it talks to
+const DefaultUserAgent = "getlexin-xml (+https://github.com/PantaKoda/getlexin-xml)"
+
+// Config describes the HTTP client
+type Config struct {
+	ConnectTimeout time.Duration // Limit for connecting, including the TLS handshake; zero for none
+	ReadTimeout    time.Duration // Limit for waiting on the response headers or the next chunk of a body; zero for none
+	ProxyURL       string        // Proxy for every request; HTTP_PROXY/HTTPS_PROXY/NO_PROXY are used if empty
+	CAFile         string        // PEM bundle of root CAs trusted in addition to the system ones
+	UserAgent      string        // Sent unless a request sets its own
+	Headers        http.Header   // Extra headers sent with every request, unless the request sets them
+}
+
+// DefaultConfig returns the configuration used when nothing else is configured
+func DefaultConfig() Config {
+	return Config{
+		ConnectTimeout: 15 * time.Second,
+		ReadTimeout:    60 * time.Second,
+		UserAgent:      DefaultUserAgent,
+	}
+}
+
+// New builds a client from cfg. There is no limit on the total duration of a
+// request, so large files can take as long as they need while data keeps coming.
+func New(cfg Config) (*http.Client, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+	base.DialContext = dialer.DialContext
+	base.TLSHandshakeTimeout = cfg.ConnectTimeout
+	base.ResponseHeaderTimeout = cfg.ReadTimeout
+
+	if cfg.ProxyURL != "" {
+		proxy, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.ProxyURL)
+		}
+		base.Proxy = http.ProxyURL(proxy)
+	}
+
+	if cfg.CAFile != "" {
+		pool, err := loadCertPool(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		base.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{
+		Transport: &transport{
+			base:        base,
+			userAgent:   cfg.UserAgent,
+			headers:     cfg.Headers,
+			readTimeout: cfg.ReadTimeout,
+		},
+	}, nil
+}
+
+// loadCertPool adds the certificates of a PEM file to the system roots
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+// transport adds the configured headers and the body read timeout to every request
+type transport struct {
+	base        http.RoundTripper
+	userAgent   string
+	headers     http.Header
+	readTimeout time.Duration
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	req = req.Clone(ctx)
+
+	for name, values := range t.headers {
+		if _, ok := req.Header[name]; !ok {
+			req.Header[name] = values
+		}
+	}
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if t.readTimeout > 0 {
+		resp.Body = newTimeoutBody(resp.Body, t.readTimeout, cancel)
+	} else {
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	}
+	return resp, nil
+}
+
+// cancelBody releases the request context once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// timeoutBody aborts the request when no data arrives for the timeout
+type timeoutBody struct {
+	rc       io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	cancel   context.CancelFunc
+	timedOut atomic.Bool
+}
+
+func newTimeoutBody(rc io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *timeoutBody {
+	b := &timeoutBody{rc: rc, timeout: timeout, cancel: cancel}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.timedOut.Store(true)
+		cancel()
+	})
+	return b
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if b.timedOut.Load() {
+		return n, fmt.Errorf("no data received for %s", b.timeout)
+	}
+	b.timer.Reset(b.timeout)
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.rc.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,82 @@
+// Package lookup searches the downloaded dictionaries for a word.
+package lookup
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+// Match is an article that contains the word, with the dictionary it came from
+type Match struct {
+	Dictionary catalog.Dictionary
+	Article    *lexin.Article
+	Terms      []string // In reverse lookups, the translations that matched
+}
+
+// Find scans the dictionaries for articles whose headword or inflected forms
+// equal word, ignoring case. With reverse set it looks word up in a
+// lexin.ReverseIndex of each dictionary instead, so translations are split and
+// normalized the same way as by the library and the API server.
+func Find(dictionaries []catalog.Dictionary, word string, reverse bool) ([]Match, error) {
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return nil, fmt.Errorf("no word to look up")
+	}
+
+	var results []Match
+	for _, dict := range dictionaries {
+		if reverse {
+			idx, err := lexin.BuildReverseIndex(dict.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", dict.File, err)
+			}
+			results = append(results, reverseMatches(dict, idx, word)...)
+			continue
+		}
+
+		for article, err := range lexin.Articles(dict.Path) {
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", dict.File, err)
+			}
+			if matchHeadword(article, word) {
+				results = append(results, Match{Dictionary: dict, Article: article})
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// reverseMatches groups the index entries for word by article, in dictionary order
+func reverseMatches(dict catalog.Dictionary, idx *lexin.ReverseIndex, word string) []Match {
+	var matches []Match
+	for _, entry := range idx.Lookup(word) {
+		if n := len(matches); n > 0 && matches[n-1].Article == entry.Article {
+			if !slices.Contains(matches[n-1].Terms, entry.Term) {
+				matches[n-1].Terms = append(matches[n-1].Terms, entry.Term)
+			}
+			continue
+		}
+		matches = append(matches, Match{Dictionary: dict, Article: entry.Article, Terms: []string{entry.Term}})
+	}
+	return matches
+}
+
+// matchHeadword reports whether a lemma of the article or one of its forms is word
+func matchHeadword(article *lexin.Article, word string) bool {
+	for _, lemma := range article.Lemmas {
+		if strings.EqualFold(lemma.Value, word) {
+			return true
+		}
+		for _, form := range lemma.Forms() {
+			if strings.EqualFold(form, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package lookup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"getlexin-xml/internal/catalog"
+)
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1">
+    <Lemma ID="1" Value="springa">
+      <Inflection>sprang</Inflection>
+      <Lexeme ID="1"><Translation>جرى؛ رَكَضَ</Translation></Lexeme>
+      <Lexeme ID="2"><Translation>جَرَى</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="2">
+    <Lemma ID="2" Value="löpa">
+      <Lexeme ID="3"><Translation>جري</Translation></Lexeme>
+    </Lemma>
+  </Article>
+</Dictionary>
+`
+
+func TestFind(t *testing.T) {
+	dict := catalog.Dictionary{Code: "arabiska", File: "swe_ara.xml", Path: filepath.Join(t.TempDir(), "swe_ara.xml")}
+	if err := os.WriteFile(dict.Path, []byte(testXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		word    string
+		reverse bool
+		want    []string // "<article id>: <matched terms>"
+	}{
+		{"Springa", false, []string{"1: []"}},
+		{"sprang", false, []string{"1: []"}},
+		{"جري", false, nil},
+		{"جري", true, []string{"1: [جرى جَرَى]", "2: [جري]"}},
+		{"ركض", true, []string{"1: [رَكَضَ]"}},
+		{"springa", true, nil},
+	}
+
+	for _, tt := range tests {
+		matches, err := Find([]catalog.Dictionary{dict}, tt.word, tt.reverse)
+		if err != nil {
+			t.Fatalf("Find(%q, %v): %v", tt.word, tt.reverse, err)
+		}
+		var got []string
+		for _, match := range matches {
+			got = append(got, match.Article.ID+": "+fmt.Sprint(match.Terms))
+			if match.Dictionary.Code != dict.Code {
+				t.Errorf("match from %q, want %q", match.Dictionary.Code, dict.Code)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%q, %v) = %q, want %q", tt.word, tt.reverse, got, tt.want)
+		}
+	}
+
+	if _, err := Find([]catalog.Dictionary{dict}, "  ", false); err == nil {
+		t.Error("Find accepted an empty word")
+	}
+}
@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the manifest in every language directory
+const FileName = "manifest.json"
+
+// Manifest describes the files downloaded for one language
+type Manifest struct {
+	Code        string      `json:"code"`
+	Name        string      `json:"name"`
+	SourceURL   string      `json:"source_url"`
+	Revision    string      `json:"revision"`
+	GeneratedAt time.Time   `json:"generated_at"`
+	Files       []FileEntry `json:"files"`
+}
+
+// FileEntry describes a single downloaded file
+type FileEntry struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	SourceURL string    `json:"source_url"`
+	Revision  string    `json:"revision"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// PartSuffix marks a file the downloader is still writing; it is not part of the language
+const PartSuffix = ".part"
+
+// QuarantineDir is the subfolder of a language directory that receives invalid XML files
+const QuarantineDir = "quarantine"
+
+// auxiliaryFiles are written by the downloader next to the dictionaries and are not listed
+var auxiliaryFiles = map[string]bool{
+	FileName:       true,
+	"index.html":   true,
+	"metadata.txt": true,
+}
+
+// Load reads the manifest of a language directory
+func Load(dirPath string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dirPath, FileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest in %s: %v", dirPath, err)
+	}
+
+	return &m, nil
+}
+
+// Write stores the manifest in a language directory
+func (m *Manifest) Write(dirPath string) error {
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Name < m.Files[j].Name })
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dirPath, FileName), append(data, '\n'), 0644)
+}
+
+// File returns the entry for a file name
+func (m *Manifest) File(name string) (FileEntry, bool) {
+	for _, entry := range m.Files {
+		if entry.Name == name {
+			return entry, true
+		}
+	}
+	return FileEntry{}, false
+}
+
+// HashFile returns the size and hex-encoded SHA-256 of a file
+func HashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Problem kinds reported by Verify
+const (
+	ProblemMissing   = "missing"
+	ProblemCorrupted = "corrupted"
+	ProblemExtra     = "extra"
+)
+
+// Problem is a difference between a manifest and the files on disk
+type Problem struct {
+	Kind   string
+	File   string
+	Detail string
+}
+
+// Verify re-hashes the files of a language directory and compares them with its manifest
+func Verify(dirPath string) ([]Problem, error) {
+	m, err := Load(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	listed := make(map[string]bool, len(m.Files))
+	for _, entry := range m.Files {
+		listed[entry.Name] = true
+
+		size, sum, err := HashFile(filepath.Join(dirPath, entry.Name))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			problems = append(problems, Problem{Kind: ProblemMissing, File: entry.Name})
+		case err != nil:
+			problems = append(problems, Problem{Kind: ProblemCorrupted, File: entry.Name, Detail: err.Error()})
+		case size != entry.Size:
+			problems = append(problems, Problem{Kind: ProblemCorrupted, File: entry.Name,
+				Detail: fmt.Sprintf("size %d, expected %d", size, entry.Size)})
+		case sum != entry.SHA256:
+			problems = append(problems, Problem{Kind: ProblemCorrupted, File: entry.Name,
+				Detail: fmt.Sprintf("sha256 %s, expected %s", sum, entry.SHA256)})
+		}
+	}
+
+	// Anything else in the directory was not served by the source. Folders such as
+	// the quarantine and the ".part" files of interrupted downloads, which the next
+	// run resumes, are not part of the language.
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || listed[name] || auxiliaryFiles[name] || strings.HasPrefix(name, ".") || strings.HasSuffix(name, PartSuffix) {
+			continue
+		}
+		problems = append(problems, Problem{Kind: ProblemExtra, File: name})
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,102 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files with the given contents in dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"swe_eng.xml":   "<Dictionary>eng</Dictionary>",
+		"swe_eng_2.xml": "<Dictionary>eng 2</Dictionary>",
+		"swe_eng_3.xml": "<Dictionary>eng 3</Dictionary>",
+		"swe_eng_4.xml": "<Dictionary>eng 4</Dictionary>",
+	})
+
+	m := &Manifest{Code: "engelska", Revision: "1234"}
+	for _, name := range []string{"swe_eng.xml", "swe_eng_2.xml", "swe_eng_3.xml", "swe_eng_4.xml"} {
+		size, sum, err := HashFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Files = append(m.Files, FileEntry{Name: name, Size: size, SHA256: sum})
+	}
+	if err := m.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("problems in an intact directory: %+v", problems)
+	}
+
+	// Lose one file, damage two, and leave the downloader's and the user's files around
+	if err := os.Remove(filepath.Join(dir, "swe_eng_2.xml")); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"swe_eng_3.xml":            "<Dictionary>eng X</Dictionary>",
+		"swe_eng_4.xml":            "<Dictionary>",
+		"notes.txt":                "mine",
+		"swe_eng_5.xml.part":       "<Dictionary>eng",
+		"index.html":               "<svn/>",
+		"metadata.txt":             "Code: engelska\n",
+		".DS_Store":                "",
+		"quarantine/swe_eng_6.xml": "<html>error</html>",
+	})
+
+	problems, err = Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// A changed file of the same size is caught by its hash
+	details := make(map[string]string)
+	for i := range problems {
+		details[problems[i].File] = problems[i].Detail
+		problems[i].Detail = ""
+	}
+	if got := details["swe_eng_3.xml"]; !strings.HasPrefix(got, "sha256 ") {
+		t.Errorf("swe_eng_3.xml detail = %q, want a hash mismatch", got)
+	}
+	if got := details["swe_eng_4.xml"]; !strings.HasPrefix(got, "size ") {
+		t.Errorf("swe_eng_4.xml detail = %q, want a size mismatch", got)
+	}
+
+	want := []Problem{
+		{Kind: ProblemMissing, File: "swe_eng_2.xml"},
+		{Kind: ProblemCorrupted, File: "swe_eng_3.xml"},
+		{Kind: ProblemCorrupted, File: "swe_eng_4.xml"},
+		{Kind: ProblemExtra, File: "notes.txt"},
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %+v, want %+v", problems, want)
+	}
+}
+
+func TestVerifyWithoutManifest(t *testing.T) {
+	if _, err := Verify(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("Verify without a manifest = %v, want a not-exist error", err)
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+)
 
 // XML structure types
 type SVN struct {
@@ -48,11 +51,53 @@ type Directory struct {
 
 // DownloadResult stores information about a download operation
 type DownloadResult struct {
-	Directory  Directory
-	Success    bool
-	FileCount  int
-	Error      error
-	TotalBytes int64
+	Directory        Directory
+	Success          bool // Every file of the directory is on disk
+	Partial          bool // The directory was processed but some files failed
+	FileCount        int  // Files fetched from the server
+	NotModifiedCount int  // Files the server reported as unchanged (304)
+	Error            error
+	TotalBytes       int64
+	Revision         string       // SVN revision of the directory listing
+	UpToDate         bool         // Skipped because the revision matched the last sync
+	Files            []FileResult // One entry per XML file in the directory
+}
+
+// FailedFiles returns the files that could not be downloaded
+func (r DownloadResult) FailedFiles() []FileResult {
+	var failed []FileResult
+	for _, file := range r.Files {
+		if file.Error != nil {
+			failed = append(failed, file)
+		}
+	}
+	return failed
+}
+
+// FileResult stores information about the download of a single file
+type FileResult struct {
+	Name        string
+	URL         string
+	Bytes       int64 // Size of the file on disk
+	Duration    time.Duration
+	HTTPStatus  int  // Status of the last response, zero if no response was received
+	NotModified bool // The local copy was kept after a 304 response
+	Error       error
+
+	QuarantinePath string // Where an invalid XML payload was moved, if any
+}
+
+// ProgressEvent reports the progress of a single file download
+type ProgressEvent struct {
+	Directory     string  // Language code
+	File          string  // File name
+	FileIndex     int     // 1-based position of the file within the directory
+	FileCount     int     // Number of XML files in the directory
+	BytesRead     int64   // Bytes of the file on disk so far, including a resumed prefix
+	ContentLength int64   // Total size of the file, -1 if unknown
+	Throughput    float64 // Bytes per second of the current transfer
+	Done          bool    // The file is finished, successfully or not
+	Error         error   // Set on the final event of a failed file
 }
 
 // LanguageMap translates directory codes to human-readable names
@@ -78,3 +123,35 @@ var LanguageMap = map[string]string{
 	"tigrinska":       "Tigrinya",
 	"turkiska":        "Turkish",
 }
+
+// LanguageTags maps directory codes to BCP 47 language tags
+var LanguageTags = map[string]string{
+	"albanska":        "sq",
+	"amhariska":       "am",
+	"arabiska":        "ar",
+	"azerbajdzjanska": "az",
+	"bosniska":        "bs",
+	"engelska":        "en",
+	"finska":          "fi",
+	"grekiska":        "el",
+	"kroatiska":       "hr",
+	"nordkurdiska":    "kmr",
+	"pashto":          "ps",
+	"persiska":        "fa",
+	"ryska":           "ru",
+	"serbiska":        "sr",
+	"somaliska":       "so",
+	"spanska":         "es",
+	"svenska":         "sv",
+	"sydkurdiska":     "ckb",
+	"tigrinska":       "ti",
+	"turkiska":        "tr",
+}
+
+// RightToLeft lists the languages whose translations are written right to left
+var RightToLeft = map[string]bool{
+	"arabiska":    true,
+	"pashto":      true,
+	"persiska":    true,
+	"sydkurdiska": true,
+}
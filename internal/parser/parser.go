@@ -4,35 +4,20 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"net/http"
 	"strings"
 
 	"getlexin-xml/internal/models"
+	"getlexin-xml/pkg/lexin"
 )
 
-// FetchDirectories fetches and parses the directory list from the lexin site
-func FetchDirectories(baseURL string) ([]models.Directory, error) {
-	// Fetch the XML from the URL
-	resp, err := http.Get(baseURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	xmlData := string(body)
-
+// ParseDirectories parses the top-level SVN listing into language directories below baseURL
+func ParseDirectories(xmlContent string, baseURL string) ([]models.Directory, error) {
 	// Clean up the XML to remove the DOCTYPE declaration which can cause parsing issues
-	xmlData = RemoveDOCTYPE(xmlData)
+	xmlContent = RemoveDOCTYPE(xmlContent)
 
 	// Parse the XML
 	var svn models.SVN
-	err = xml.Unmarshal([]byte(xmlData), &svn)
+	err := xml.Unmarshal([]byte(xmlContent), &svn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse XML: %v", err)
 	}
@@ -40,25 +25,30 @@ func FetchDirectories(baseURL string) ([]models.Directory, error) {
 	// Extract directories with translated names
 	var directories []models.Directory
 	for _, dir := range svn.Index.Dirs {
-		langName, ok := models.LanguageMap[dir.Name]
-		if !ok {
-			langName = strings.Title(dir.Name) // Capitalize if not in map
-		}
-
-		directories = append(directories, models.Directory{
-			Code:        dir.Name,
-			Name:        langName,
-			URL:         baseURL + dir.Href,
-			Description: fmt.Sprintf("Swedish-%s lexicon", langName),
-			Selected:    false,
-		})
+		directories = append(directories, NewDirectory(dir.Name, baseURL+dir.Href))
 	}
 
 	return directories, nil
 }
 
-// ParseDirectoryContents parses a directory's XML content and returns XML filenames
-func ParseDirectoryContents(xmlContent string) ([]models.File, error) {
+// NewDirectory describes the language directory code found at url
+func NewDirectory(code string, url string) models.Directory {
+	langName, ok := models.LanguageMap[code]
+	if !ok {
+		langName = strings.Title(code) // Capitalize if not in map
+	}
+
+	return models.Directory{
+		Code:        code,
+		Name:        langName,
+		URL:         url,
+		Description: fmt.Sprintf("Swedish-%s lexicon", langName),
+		Selected:    false,
+	}
+}
+
+// ParseIndex parses a directory's XML content and returns the SVN index, including its revision
+func ParseIndex(xmlContent string) (models.Index, error) {
 	// Clean up the XML
 	xmlContent = RemoveDOCTYPE(xmlContent)
 
@@ -66,18 +56,68 @@ func ParseDirectoryContents(xmlContent string) ([]models.File, error) {
 	var svn models.SVN
 	err := xml.Unmarshal([]byte(xmlContent), &svn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse directory XML: %v", err)
+		return models.Index{}, fmt.Errorf("failed to parse directory XML: %v", err)
 	}
 
-	// Filter files to include only XML files
+	return svn.Index, nil
+}
+
+// XMLFiles filters the files of an index to include only XML files
+func XMLFiles(index models.Index) []models.File {
 	var xmlFiles []models.File
-	for _, file := range svn.Index.Files {
+	for _, file := range index.Files {
 		if strings.HasSuffix(file.Href, ".xml") {
 			xmlFiles = append(xmlFiles, file)
 		}
 	}
 
-	return xmlFiles, nil
+	return xmlFiles
+}
+
+// ValidateXML reads r to the end and reports whether it is a well-formed XML document
+// with a single root element
+func ValidateXML(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = lexin.CharsetReader
+
+	depth := 0
+	roots := 0
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+				if roots > 1 {
+					line, _ := decoder.InputPos()
+					return fmt.Errorf("line %d: second root element <%s>", line, t.Name.Local)
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(strings.TrimSpace(string(t))) > 0 {
+				return fmt.Errorf("text outside of the root element")
+			}
+		}
+	}
+
+	if roots == 0 {
+		return fmt.Errorf("no root element")
+	}
+	if depth != 0 {
+		return fmt.Errorf("unexpected end of document")
+	}
+
+	return nil
 }
 
 // RemoveDOCTYPE removes DOCTYPE declaration from XML string
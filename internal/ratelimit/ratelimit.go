@@ -0,0 +1,115 @@
+// Package ratelimit paces requests and transferred bytes with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket refilled at a steady rate. A nil *Bucket never waits.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens added per second
+	burst  float64 // Capacity of the bucket
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket creates a full bucket; it returns nil (no limit) if rate is not positive
+func NewBucket(rate float64, burst int) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(max(burst, 1))
+	return &Bucket{rate: rate, burst: b, tokens: b, last: time.Now()}
+}
+
+// WaitN takes n tokens, waiting until the bucket has refilled enough or ctx is done.
+// Requests larger than the bucket go into debt, which later callers wait for.
+func (b *Bucket) WaitN(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return ctx.Err()
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the unused tokens back
+		b.mu.Lock()
+		b.tokens += float64(n)
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Limiter caps the request rate and the bandwidth of everything that shares it.
+// A nil *Limiter does not limit anything.
+type Limiter struct {
+	Requests *Bucket // One token per request
+	Bytes    *Bucket // One token per byte
+}
+
+// New creates a limiter for requestsPerSecond and bytesPerSecond; zero means unlimited.
+// Requests are spaced evenly, and bytes may burst by up to a second's worth.
+func New(requestsPerSecond float64, bytesPerSecond int64) *Limiter {
+	return &Limiter{
+		Requests: NewBucket(requestsPerSecond, 1),
+		Bytes:    NewBucket(float64(bytesPerSecond), int(min(bytesPerSecond, math.MaxInt32))),
+	}
+}
+
+// WaitRequest waits until another request may be made
+func (l *Limiter) WaitRequest(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	return l.Requests.WaitN(ctx, 1)
+}
+
+// Reader paces reads from r to the byte rate
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil || l.Bytes == nil {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, bucket: l.Bytes}
+}
+
+// reader takes a token for every byte it reads
+type reader struct {
+	ctx    context.Context
+	r      io.Reader
+	bucket *Bucket
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	// Keep single reads below the bucket size so throughput stays smooth
+	if limit := int(r.bucket.burst); len(p) > limit {
+		p = p[:limit]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.bucket.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
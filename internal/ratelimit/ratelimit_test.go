@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// balance returns the current number of tokens in the bucket
+func (b *Bucket) balance() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+func TestNewBucketWithoutRateIsUnlimited(t *testing.T) {
+	b := NewBucket(0, 10)
+	if b != nil {
+		t.Fatalf("NewBucket(0, 10) = %+v, want nil", b)
+	}
+	if err := b.WaitN(context.Background(), 1_000_000); err != nil {
+		t.Errorf("WaitN on a nil bucket: %v", err)
+	}
+}
+
+func TestBucketWaitsForRefill(t *testing.T) {
+	b := NewBucket(20, 1) // One token every 50ms
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 3 {
+		if err := b.WaitN(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first token is in the bucket, the other two take 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("three requests took %v, want at least 100ms", elapsed)
+	}
+}
+
+func TestBucketDebt(t *testing.T) {
+	b := NewBucket(100, 10)
+	ctx := context.Background()
+
+	// Taking more than the bucket holds goes into debt and waits it off
+	start := time.Now()
+	if err := b.WaitN(ctx, 30); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("taking 30 tokens from a bucket of 10 took %v, want about 200ms", elapsed)
+	}
+
+	// The waiter has paid the debt; the bucket is empty, not full
+	if got := b.balance(); got > 0 {
+		t.Errorf("balance after the debt = %v, want at most 0", got)
+	}
+}
+
+func TestBucketCancelReturnsTokens(t *testing.T) {
+	b := NewBucket(1, 1)
+
+	// Empty the bucket, then give up on waiting for the next token
+	if err := b.WaitN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := b.WaitN(ctx, 5); err != context.Canceled {
+		t.Fatalf("WaitN with a cancelled context = %v, want %v", err, context.Canceled)
+	}
+
+	// Only the refill since the first call may remain, the 5 tokens were given back
+	if got := b.balance(); got < -0.1 || got > 0.1 {
+		t.Errorf("balance after cancel = %v, want about 0", got)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	if err := l.WaitRequest(context.Background()); err != nil {
+		t.Errorf("WaitRequest: %v", err)
+	}
+
+	r := strings.NewReader("data")
+	if got := l.Reader(context.Background(), r); got != io.Reader(r) {
+		t.Errorf("Reader on a nil limiter wrapped the reader")
+	}
+	if got := New(5, 0).Reader(context.Background(), r); got != io.Reader(r) {
+		t.Errorf("Reader without a byte limit wrapped the reader")
+	}
+}
+
+func TestLimiterReader(t *testing.T) {
+	const rate = 1000
+	l := New(0, rate)
+	data := bytes.Repeat([]byte("x"), 1500)
+
+	start := time.Now()
+	r := l.Reader(context.Background(), bytes.NewReader(data))
+
+	// Single reads never exceed the burst
+	buf := make([]byte, 4096)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n > rate {
+		t.Errorf("read %d bytes at once, want at most %d", n, rate)
+	}
+
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n + len(rest); got != len(data) {
+		t.Errorf("read %d bytes, want %d", got, len(data))
+	}
+
+	// The first second's worth is a burst, the remaining 500 bytes take half a second
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("reading 1500 bytes at %d bytes/s took %v, want about 500ms", rate, elapsed)
+	}
+}
+
+func TestLimiterReaderCancel(t *testing.T) {
+	l := New(0, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	r := l.Reader(ctx, bytes.NewReader(bytes.Repeat([]byte("x"), 1000)))
+
+	// Use up the burst, then cancel while waiting for the next chunk
+	if _, err := r.Read(make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	start := time.Now()
+	n, err := r.Read(make([]byte, 100))
+	if err != context.Canceled {
+		t.Errorf("Read after cancel = %v, want %v", err, context.Canceled)
+	}
+	if n != 100 {
+		t.Errorf("Read after cancel returned %d bytes, want the 100 read from the source", n)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Read kept waiting for %v after cancel", elapsed)
+	}
+}
+
+func TestNewLimiterBurst(t *testing.T) {
+	l := New(2, math.MaxInt64)
+	if l.Bytes.burst != math.MaxInt32 {
+		t.Errorf("byte burst = %v, want it capped at %d", l.Bytes.burst, math.MaxInt32)
+	}
+	if l.Requests.burst != 1 {
+		t.Errorf("request burst = %v, want 1", l.Requests.burst)
+	}
+}
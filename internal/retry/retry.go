@@ -0,0 +1,188 @@
+package retry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy describes how failed HTTP operations are retried
+type Policy struct {
+	MaxAttempts int           // Total number of attempts, including the first one
+	BaseDelay   time.Duration // Delay before the first retry, doubled for every further retry
+	MaxDelay    time.Duration // Upper bound for a single delay, including Retry-After
+	Jitter      float64       // Fraction (0-1) of each delay that is randomized
+
+	// Notify is called before sleeping ahead of a retry (optional)
+	Notify func(attempt int, delay time.Duration, err error)
+}
+
+// DefaultPolicy returns the retry policy used when nothing else is configured
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.5,
+	}
+}
+
+// StatusError reports an unexpected HTTP status code
+type StatusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration // Parsed Retry-After header, zero if absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("bad status: %s", e.Status)
+}
+
+// Temporary reports whether the status is worth retrying
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// NewStatusError builds a StatusError from a response
+func NewStatusError(resp *http.Response) *StatusError {
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Do returns it immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the attempts run out or ctx is done
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	attempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+
+		// Give up on cancellation, permanent failures and on the last attempt
+		if ctx.Err() != nil {
+			return err
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if isCertificateError(err) {
+			return err
+		}
+		var statusErr *StatusError
+		isStatus := errors.As(err, &statusErr)
+		if isStatus && !statusErr.Temporary() {
+			return err
+		}
+		if attempt >= attempts {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		if isStatus && statusErr.RetryAfter > 0 {
+			delay = statusErr.RetryAfter
+			if p.MaxDelay > 0 && delay > p.MaxDelay {
+				delay = p.MaxDelay
+			}
+		}
+
+		if p.Notify != nil {
+			p.Notify(attempt, delay, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// isCertificateError reports whether err comes from a failed TLS handshake or certificate
+// check, which fails the same way on every attempt
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr)
+}
+
+// backoff returns the jittered exponential delay before retry number attempt
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// Randomize the configured fraction of the delay to spread out retries
+	jitter := min(max(p.Jitter, 0), 1)
+	if jitter > 0 && delay > 0 {
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+
+	return delay
+}
+
+// parseRetryAfter understands both the delay-seconds and HTTP-date forms
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,260 @@
+package retry
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffDoublesUpToMaxDelay(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestBackoffJitterRange(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.25}
+
+	for range 1000 {
+		got := p.backoff(1)
+		if got < 750*time.Millisecond || got > time.Second {
+			t.Fatalf("backoff(1) = %v, want between 750ms and 1s", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		min, max time.Duration
+	}{
+		{"empty", "", 0, 0},
+		{"seconds", "5", 5 * time.Second, 5 * time.Second},
+		{"negative seconds", "-3", 0, 0},
+		{"garbage", "soon", 0, 0},
+		{"future date", time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+		{"past date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value)
+			if got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestDoClampsRetryAfterToMaxDelay(t *testing.T) {
+	p := Policy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	var delays []time.Duration
+	p.Notify = func(attempt int, delay time.Duration, err error) {
+		delays = append(delays, delay)
+	}
+
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return &StatusError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", RetryAfter: time.Hour}
+	})
+
+	if err == nil {
+		t.Fatal("Do succeeded, want the last error")
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+	if len(delays) != 1 || delays[0] != p.MaxDelay {
+		t.Errorf("delays = %v, want [%v]", delays, p.MaxDelay)
+	}
+}
+
+func TestDoStopsOnPermanentErrors(t *testing.T) {
+	sentinel := errors.New("disk full")
+
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{"permanent", Permanent(sentinel), sentinel},
+		{"client error", &StatusError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}, nil},
+		{"unknown certificate authority", x509.UnknownAuthorityError{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{MaxAttempts: 5, BaseDelay: time.Millisecond}
+
+			calls := 0
+			err := p.Do(context.Background(), func() error {
+				calls++
+				return tt.err
+			})
+
+			if calls != 1 {
+				t.Errorf("got %d calls, want 1", calls)
+			}
+			want := tt.wantErr
+			if want == nil {
+				want = tt.err
+			}
+			if err != want {
+				t.Errorf("Do returned %v, want %v", err, want)
+			}
+		})
+	}
+}
+
+func TestPermanentNil(t *testing.T) {
+	if err := Permanent(nil); err != nil {
+		t.Errorf("Permanent(nil) = %v, want nil", err)
+	}
+}
+
+func TestDoCancelDuringSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := Policy{MaxAttempts: 3, BaseDelay: time.Hour}
+	p.Notify = func(int, time.Duration, error) { cancel() }
+
+	sentinel := errors.New("connection reset")
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- p.Do(ctx, func() error {
+			calls++
+			return sentinel
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != sentinel {
+			t.Errorf("Do returned %v, want %v", err, sentinel)
+		}
+		if calls != 1 {
+			t.Errorf("got %d calls, want 1", calls)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do kept sleeping after ctx was cancelled")
+	}
+}
+
+func TestDoRetriesStatusErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int // Status of each response; the last one repeats
+		wantStatus int   // StatusCode of the returned StatusError, 0 for success
+		wantCalls  int32
+	}{
+		{"ok", []int{http.StatusOK}, 0, 1},
+		{"server error then ok", []int{http.StatusServiceUnavailable, http.StatusOK}, 0, 2},
+		{"too many requests then ok", []int{http.StatusTooManyRequests, http.StatusOK}, 0, 2},
+		{"not found is not retried", []int{http.StatusNotFound}, http.StatusNotFound, 1},
+		{"server errors until attempts run out", []int{http.StatusInternalServerError}, http.StatusInternalServerError, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				status := tt.statuses[min(n, len(tt.statuses))-1]
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", strconv.Itoa(60))
+				}
+				w.WriteHeader(status)
+				io.WriteString(w, "body")
+			}))
+			defer srv.Close()
+
+			p := Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+			var body []byte
+			err := p.Do(context.Background(), func() error {
+				resp, err := srv.Client().Get(srv.URL)
+				if err != nil {
+					return err
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					return NewStatusError(resp)
+				}
+				body, err = io.ReadAll(resp.Body)
+				return err
+			})
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("got %d requests, want %d", got, tt.wantCalls)
+			}
+
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("Do: %v", err)
+				}
+				if string(body) != "body" {
+					t.Errorf("body = %q, want %q", body, "body")
+				}
+				return
+			}
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("Do error = %v, want a *StatusError", err)
+			}
+			if statusErr.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", statusErr.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDoDoesNotRetryCertificateErrors(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	p := Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	retries := 0
+	p.Notify = func(int, time.Duration, error) { retries++ }
+
+	// The default client does not trust the test server's certificate
+	err := p.Do(context.Background(), func() error {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	if err == nil {
+		t.Fatal("request succeeded against an untrusted certificate")
+	}
+	if retries != 0 {
+		t.Errorf("certificate error retried %d times", retries)
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+// dictionaryIndex holds the articles of one dictionary in memory, indexed by
+// lower-cased headword and inflected form, and by translation for reverse lookups
+type dictionaryIndex struct {
+	dict     catalog.Dictionary
+	articles []*lexin.Article
+	byID     map[string]int
+	words    map[string][]int // Folded word -> article positions
+	keys     []string         // Sorted keys of words, for prefix search
+	reverse  *lexin.ReverseIndex
+}
+
+// loadIndex reads a dictionary file and builds its index
+func loadIndex(dict catalog.Dictionary) (*dictionaryIndex, error) {
+	idx := &dictionaryIndex{
+		dict:    dict,
+		byID:    make(map[string]int),
+		words:   make(map[string][]int),
+		reverse: lexin.NewReverseIndex(),
+	}
+
+	for article, err := range lexin.Articles(dict.Path) {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", dict.File, err)
+		}
+		pos := len(idx.articles)
+		idx.articles = append(idx.articles, article)
+		idx.byID[article.ID] = pos
+		idx.reverse.Add(article)
+
+		for _, lemma := range article.Lemmas {
+			idx.add(lemma.Value, pos)
+			for _, form := range lemma.Forms() {
+				idx.add(form, pos)
+			}
+		}
+	}
+
+	idx.keys = make([]string, 0, len(idx.words))
+	for key := range idx.words {
+		idx.keys = append(idx.keys, key)
+	}
+	sort.Strings(idx.keys)
+
+	return idx, nil
+}
+
+// add records that the article at pos can be found under word
+func (idx *dictionaryIndex) add(word string, pos int) {
+	key := fold(word)
+	if key == "" {
+		return
+	}
+	positions := idx.words[key]
+	if len(positions) > 0 && positions[len(positions)-1] == pos {
+		return
+	}
+	idx.words[key] = append(positions, pos)
+}
+
+// lookup returns the articles whose headword or a form equals word
+func (idx *dictionaryIndex) lookup(word string) []*lexin.Article {
+	var articles []*lexin.Article
+	for _, pos := range idx.words[fold(word)] {
+		articles = append(articles, idx.articles[pos])
+	}
+	return articles
+}
+
+// prefix returns the positions of the articles with a word starting with prefix,
+// in word order and without duplicates
+func (idx *dictionaryIndex) prefix(prefix string) []int {
+	prefix = fold(prefix)
+	seen := make(map[int]bool)
+	var positions []int
+	for i := sort.SearchStrings(idx.keys, prefix); i < len(idx.keys) && strings.HasPrefix(idx.keys[i], prefix); i++ {
+		for _, pos := range idx.words[idx.keys[i]] {
+			if !seen[pos] {
+				seen[pos] = true
+				positions = append(positions, pos)
+			}
+		}
+	}
+	return positions
+}
+
+// fold normalizes a word for case-insensitive matching
+func fold(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
@@ -0,0 +1,314 @@
+// Package server exposes the downloaded dictionaries as a JSON HTTP API.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"getlexin-xml/internal/catalog"
+	"getlexin-xml/pkg/lexin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Options configures the API
+type Options struct {
+	// AllowedOrigins lists the origins browsers may call the API from; "*" allows any.
+	// Without any, no CORS headers are sent.
+	AllowedOrigins []string
+}
+
+// Server answers API requests from dictionaries loaded into memory
+type Server struct {
+	indexes []*dictionaryIndex
+	byCode  map[string][]*dictionaryIndex
+	byPair  map[string]*dictionaryIndex
+	origins []string
+	mux     *http.ServeMux
+}
+
+// Language describes a loaded dictionary
+type Language struct {
+	Code     string `json:"code"`
+	Language string `json:"language"`
+	Pair     string `json:"pair"`
+	File     string `json:"file"`
+	Revision string `json:"revision,omitempty"`
+	Articles int    `json:"articles"`
+}
+
+// ArticleResult is a full article with the id it can be fetched by
+type ArticleResult struct {
+	ID       string         `json:"id"`
+	Language string         `json:"language"`
+	Article  *lexin.Article `json:"article"`
+}
+
+// ReverseResult is a Swedish article found from a target-language term
+type ReverseResult struct {
+	ArticleResult
+	Term     string `json:"term"`      // The translation that matched, as written
+	LexemeID string `json:"lexeme_id"` // The sense the translation belongs to
+}
+
+// SearchHit is a short summary of an article in search results
+type SearchHit struct {
+	ID           string   `json:"id"`
+	Language     string   `json:"language"`
+	Headword     string   `json:"headword"`
+	WordClass    string   `json:"word_class,omitempty"`
+	Translations []string `json:"translations"`
+}
+
+// SearchPage is one page of search results
+type SearchPage struct {
+	Query   string      `json:"query"`
+	Total   int         `json:"total"`
+	Offset  int         `json:"offset"`
+	Limit   int         `json:"limit"`
+	Results []SearchHit `json:"results"`
+}
+
+// New loads the dictionaries and sets up the routes
+func New(dictionaries []catalog.Dictionary, opts Options) (*Server, error) {
+	s := &Server{
+		byCode:  make(map[string][]*dictionaryIndex),
+		byPair:  make(map[string]*dictionaryIndex),
+		origins: opts.AllowedOrigins,
+		mux:     http.NewServeMux(),
+	}
+
+	for _, dict := range dictionaries {
+		idx, err := loadIndex(dict)
+		if err != nil {
+			return nil, err
+		}
+		s.indexes = append(s.indexes, idx)
+		s.byCode[dict.Code] = append(s.byCode[dict.Code], idx)
+		s.byPair[dict.ID()] = idx
+	}
+
+	s.mux.HandleFunc("GET /languages", s.handleLanguages)
+	s.mux.HandleFunc("GET /lookup/{lang}/{word}", s.handleLookup)
+	s.mux.HandleFunc("GET /reverse/{lang}/{word}", s.handleReverse)
+	s.mux.HandleFunc("GET /search", s.handleSearch)
+	s.mux.HandleFunc("GET /articles/{id}", s.handleArticle)
+
+	return s, nil
+}
+
+// ServeHTTP adds the CORS headers and dispatches the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	anyOrigin := slices.Contains(s.origins, "*")
+	if len(s.origins) > 0 && !anyOrigin {
+		// The response depends on the origin even when it is not allowed, so
+		// caches must not hand an allowed response to other origins
+		w.Header().Add("Vary", "Origin")
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" && len(s.origins) > 0 {
+		if anyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if slices.Contains(s.origins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		// Answer preflight requests before routing, the API itself only has GET routes
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.Header().Set("Access-Control-Max-Age", "3600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleLanguages lists the loaded dictionaries
+func (s *Server) handleLanguages(w http.ResponseWriter, r *http.Request) {
+	languages := make([]Language, 0, len(s.indexes))
+	for _, idx := range s.indexes {
+		languages = append(languages, Language{
+			Code:     idx.dict.Code,
+			Language: idx.dict.Language,
+			Pair:     idx.dict.ID(),
+			File:     idx.dict.File,
+			Revision: idx.dict.Revision,
+			Articles: len(idx.articles),
+		})
+	}
+	writeJSON(w, http.StatusOK, languages)
+}
+
+// handleLookup returns the articles whose headword or an inflected form is the word
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	lang, word := r.PathValue("lang"), r.PathValue("word")
+	indexes, ok := s.byCode[lang]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown language %q", lang)
+		return
+	}
+
+	results := []ArticleResult{}
+	for _, idx := range indexes {
+		for _, article := range idx.lookup(word) {
+			results = append(results, articleResult(idx, article))
+		}
+	}
+	if len(results) == 0 {
+		writeError(w, http.StatusNotFound, "%q not found", word)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"language": lang, "word": word, "results": results})
+}
+
+// handleReverse returns the Swedish articles whose translations include the word
+func (s *Server) handleReverse(w http.ResponseWriter, r *http.Request) {
+	lang, word := r.PathValue("lang"), r.PathValue("word")
+	indexes, ok := s.byCode[lang]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown language %q", lang)
+		return
+	}
+
+	results := []ReverseResult{}
+	for _, idx := range indexes {
+		for _, entry := range idx.reverse.Lookup(word) {
+			results = append(results, ReverseResult{
+				ArticleResult: articleResult(idx, entry.Article),
+				Term:          entry.Term,
+				LexemeID:      entry.Lexeme.ID,
+			})
+		}
+	}
+	if len(results) == 0 {
+		writeError(w, http.StatusNotFound, "%q not found", word)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"language": lang, "word": word, "results": results})
+}
+
+// handleSearch pages through the articles with a headword or form starting with q
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	offset, err := intParam(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "invalid offset %q", query.Get("offset"))
+		return
+	}
+	limit, err := intParam(query.Get("limit"), defaultPageSize)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "invalid limit %q", query.Get("limit"))
+		return
+	}
+	limit = min(limit, maxPageSize)
+
+	indexes := s.indexes
+	if langs := query.Get("lang"); langs != "" {
+		indexes = nil
+		for _, code := range catalog.ParseCodes(langs) {
+			selected, ok := s.byCode[code]
+			if !ok {
+				writeError(w, http.StatusNotFound, "unknown language %q", code)
+				return
+			}
+			indexes = append(indexes, selected...)
+		}
+	}
+
+	page := SearchPage{Query: q, Offset: offset, Limit: limit, Results: []SearchHit{}}
+	for _, idx := range indexes {
+		positions := idx.prefix(q)
+		for _, pos := range positions[min(max(offset-page.Total, 0), len(positions)):] {
+			if len(page.Results) == limit {
+				break
+			}
+			page.Results = append(page.Results, searchHit(idx, idx.articles[pos]))
+		}
+		page.Total += len(positions)
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleArticle returns a single article by its id
+func (s *Server) handleArticle(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	pair, key, ok := strings.Cut(id, ":")
+	idx := s.byPair[pair]
+	if !ok || idx == nil {
+		writeError(w, http.StatusNotFound, "unknown article %q", id)
+		return
+	}
+	pos, ok := idx.byID[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown article %q", id)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, articleResult(idx, idx.articles[pos]))
+}
+
+// articleID identifies an article across dictionaries as "<pair>:<article id>"
+func articleID(idx *dictionaryIndex, article *lexin.Article) string {
+	return idx.dict.ID() + ":" + article.ID
+}
+
+func articleResult(idx *dictionaryIndex, article *lexin.Article) ArticleResult {
+	return ArticleResult{ID: articleID(idx, article), Language: idx.dict.Code, Article: article}
+}
+
+func searchHit(idx *dictionaryIndex, article *lexin.Article) SearchHit {
+	hit := SearchHit{
+		ID:           articleID(idx, article),
+		Language:     idx.dict.Code,
+		Headword:     article.Headword(),
+		Translations: article.Translations(),
+	}
+	if hit.Translations == nil {
+		hit.Translations = []string{}
+	}
+	if len(article.Lemmas) > 0 {
+		hit.WordClass = article.Lemmas[0].WordClass
+	}
+	return hit
+}
+
+// intParam parses an optional integer query parameter
+func intParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"getlexin-xml/internal/catalog"
+)
+
+// testDictionaries are the fixture files, by file name
+var testDictionaries = map[string]string{
+	"swe_eng.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1">
+    <Lemma ID="1" Value="hus" Type="subst.">
+      <Inflection>huset</Inflection>
+      <Lexeme ID="1"><Translation>house</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="2">
+    <Lemma ID="2" Value="hund" Type="subst.">
+      <Lexeme ID="2"><Translation>dog</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="3">
+    <Lemma ID="3" Value="bil" Type="subst.">
+      <Lexeme ID="3"><Translation>car</Translation></Lexeme>
+    </Lemma>
+  </Article>
+</Dictionary>
+`,
+	"swe_ara.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1">
+    <Lemma ID="1" Value="hus" Type="subst.">
+      <Lexeme ID="1"><Translation>بيت</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="2">
+    <Lemma ID="2" Value="humor" Type="subst.">
+      <Lexeme ID="2"><Translation>فكاهة</Translation></Lexeme>
+    </Lemma>
+  </Article>
+</Dictionary>
+`,
+}
+
+// newTestServer loads the fixture dictionaries, English first, and serves them
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	var dictionaries []catalog.Dictionary
+	for _, d := range []struct{ code, language, file string }{
+		{"engelska", "English", "swe_eng.xml"},
+		{"arabiska", "Arabic", "swe_ara.xml"},
+	} {
+		path := filepath.Join(dir, d.file)
+		if err := os.WriteFile(path, []byte(testDictionaries[d.file]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dictionaries = append(dictionaries, catalog.Dictionary{Code: d.code, Language: d.language, File: d.file, Path: path})
+	}
+
+	s, err := New(dictionaries, opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getJSON requests path and decodes the response into body, returning the status
+func getJSON(t *testing.T, srv *httptest.Server, path string, body any) int {
+	t.Helper()
+
+	resp, err := srv.Client().Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("%s: Content-Type = %q", path, got)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		t.Fatalf("%s: decoding response: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestLanguages(t *testing.T) {
+	srv := newTestServer(t, Options{})
+
+	var languages []Language
+	if status := getJSON(t, srv, "/languages", &languages); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	want := []Language{
+		{Code: "engelska", Language: "English", Pair: "swe_eng", File: "swe_eng.xml", Articles: 3},
+		{Code: "arabiska", Language: "Arabic", Pair: "swe_ara", File: "swe_ara.xml", Articles: 2},
+	}
+	if len(languages) != len(want) {
+		t.Fatalf("got %d languages, want %d", len(languages), len(want))
+	}
+	for i := range want {
+		if languages[i] != want[i] {
+			t.Errorf("languages[%d] = %+v, want %+v", i, languages[i], want[i])
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	srv := newTestServer(t, Options{})
+
+	var hit struct {
+		Results []ArticleResult `json:"results"`
+	}
+	if status := getJSON(t, srv, "/lookup/engelska/Huset", &hit); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if len(hit.Results) != 1 || hit.Results[0].ID != "swe_eng:1" {
+		t.Fatalf("results = %+v, want the article swe_eng:1", hit.Results)
+	}
+	if got := hit.Results[0].Article.Headword(); got != "hus" {
+		t.Errorf("headword = %q, want %q", got, "hus")
+	}
+
+	for _, path := range []string{"/lookup/engelska/katt", "/lookup/tyska/hus"} {
+		var body map[string]string
+		if status := getJSON(t, srv, path, &body); status != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", path, status, http.StatusNotFound)
+		}
+		if body["error"] == "" {
+			t.Errorf("%s: no error message", path)
+		}
+	}
+}
+
+func TestReverse(t *testing.T) {
+	srv := newTestServer(t, Options{})
+
+	// The term is normalized like the index, so a heh finds a teh marbuta
+	for _, tt := range []struct {
+		word, wantID, wantTerm, wantLexeme string
+	}{
+		{"بيت", "swe_ara:1", "بيت", "1"},
+		{"فكاهه", "swe_ara:2", "فكاهة", "2"},
+	} {
+		var hit struct {
+			Results []ReverseResult `json:"results"`
+		}
+		if status := getJSON(t, srv, "/reverse/arabiska/"+tt.word, &hit); status != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.word, status, http.StatusOK)
+		}
+		if len(hit.Results) != 1 {
+			t.Fatalf("%s: results = %+v, want one", tt.word, hit.Results)
+		}
+		result := hit.Results[0]
+		if result.ID != tt.wantID || result.Term != tt.wantTerm || result.LexemeID != tt.wantLexeme {
+			t.Errorf("%s: result %s, term %q, sense %s, want %s, %q, %s",
+				tt.word, result.ID, result.Term, result.LexemeID, tt.wantID, tt.wantTerm, tt.wantLexeme)
+		}
+		if result.Article == nil || result.Language != "arabiska" {
+			t.Errorf("%s: result without its article or language: %+v", tt.word, result)
+		}
+	}
+
+	for _, path := range []string{"/reverse/arabiska/house", "/reverse/tyska/haus"} {
+		var body map[string]string
+		if status := getJSON(t, srv, path, &body); status != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", path, status, http.StatusNotFound)
+		}
+	}
+}
+
+func TestSearchPagesAcrossDictionaries(t *testing.T) {
+	srv := newTestServer(t, Options{})
+
+	tests := []struct {
+		path      string
+		wantTotal int
+		wantIDs   []string
+	}{
+		// English matches come first (hund, hus), then Arabic (humor, hus)
+		{"/search?q=hu", 4, []string{"swe_eng:2", "swe_eng:1", "swe_ara:2", "swe_ara:1"}},
+		{"/search?q=hu&offset=1&limit=2", 4, []string{"swe_eng:1", "swe_ara:2"}},
+		{"/search?q=hu&offset=3&limit=5", 4, []string{"swe_ara:1"}},
+		{"/search?q=hu&offset=10", 4, []string{}},
+		{"/search?q=hu&lang=arabiska", 2, []string{"swe_ara:2", "swe_ara:1"}},
+	}
+
+	for _, tt := range tests {
+		var page SearchPage
+		if status := getJSON(t, srv, tt.path, &page); status != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", tt.path, status, http.StatusOK)
+			continue
+		}
+
+		if page.Total != tt.wantTotal {
+			t.Errorf("%s: total = %d, want %d", tt.path, page.Total, tt.wantTotal)
+		}
+
+		ids := []string{}
+		for _, hit := range page.Results {
+			ids = append(ids, hit.ID)
+		}
+		if len(ids) != len(tt.wantIDs) {
+			t.Errorf("%s: results = %v, want %v", tt.path, ids, tt.wantIDs)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tt.wantIDs[i] {
+				t.Errorf("%s: results = %v, want %v", tt.path, ids, tt.wantIDs)
+				break
+			}
+		}
+	}
+
+	for _, path := range []string{"/search", "/search?q=hu&offset=-1", "/search?q=hu&limit=0"} {
+		var body map[string]string
+		if status := getJSON(t, srv, path, &body); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", path, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestArticle(t *testing.T) {
+	srv := newTestServer(t, Options{})
+
+	var result ArticleResult
+	if status := getJSON(t, srv, "/articles/swe_ara:2", &result); status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if result.ID != "swe_ara:2" || result.Language != "arabiska" {
+		t.Errorf("result = %+v, want swe_ara:2 in arabiska", result)
+	}
+	if got := result.Article.Headword(); got != "humor" {
+		t.Errorf("headword = %q, want %q", got, "humor")
+	}
+
+	for _, path := range []string{"/articles/swe_ara:9", "/articles/swe_deu:1", "/articles/swe_ara"} {
+		var body map[string]string
+		if status := getJSON(t, srv, path, &body); status != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", path, status, http.StatusNotFound)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	const allowed = "https://app.example"
+
+	tests := []struct {
+		name       string
+		origins    []string
+		method     string
+		origin     string
+		wantStatus int
+		wantAllow  string // Access-Control-Allow-Origin
+		wantVary   bool
+	}{
+		{"preflight from allowed origin", []string{allowed}, http.MethodOptions, allowed, http.StatusNoContent, allowed, true},
+		{"request from allowed origin", []string{allowed}, http.MethodGet, allowed, http.StatusOK, allowed, true},
+		{"request from other origin", []string{allowed}, http.MethodGet, "https://evil.example", http.StatusOK, "", true},
+		{"request without origin", []string{allowed}, http.MethodGet, "", http.StatusOK, "", true},
+		{"any origin", []string{"*"}, http.MethodGet, "https://evil.example", http.StatusOK, "*", false},
+		{"cors disabled", nil, http.MethodGet, allowed, http.StatusOK, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, Options{AllowedOrigins: tt.origins})
+
+			req, err := http.NewRequest(tt.method, srv.URL+"/languages", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				req.Header.Set("Access-Control-Request-Headers", "Accept")
+			}
+
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllow)
+			}
+			if got := resp.Header.Get("Vary") == "Origin"; got != tt.wantVary {
+				t.Errorf("Vary = %q, want Origin: %v", resp.Header.Get("Vary"), tt.wantVary)
+			}
+			if tt.method == http.MethodOptions {
+				if got := resp.Header.Get("Access-Control-Allow-Methods"); got != "GET, OPTIONS" {
+					t.Errorf("Access-Control-Allow-Methods = %q", got)
+				}
+				if got := resp.Header.Get("Access-Control-Allow-Headers"); got != "Accept" {
+					t.Errorf("Access-Control-Allow-Headers = %q", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"getlexin-xml/internal/manifest"
+	"getlexin-xml/internal/models"
+	"getlexin-xml/internal/parser"
+	"getlexin-xml/internal/retry"
+)
+
+// Archive formats
+const (
+	formatZip   = "zip"
+	formatTar   = "tar"
+	formatTarGz = "tar.gz"
+)
+
+// Archive reads a snapshot of a mirror packed as a zip or (gzipped) tar file.
+// Language directories are the folders holding XML files, at any depth, so an
+// archive of the whole output directory works as well as one of its contents.
+//
+// The archive is indexed once when it is opened. A gzipped tar is unpacked to a
+// temporary tar file first, so files can be read at any offset without
+// decompressing the archive again for each of them.
+type Archive struct {
+	Path    string
+	format  string
+	file    *os.File                // The archive, or the unpacked tar of a gzipped one
+	entries map[string]archiveEntry // Regular files by slash-separated name
+	dirs    map[string]string       // Folder of each language, by code
+
+	unpacked string // Temporary file to remove on Close
+}
+
+// archiveEntry is a file inside an archive
+type archiveEntry struct {
+	size    int64
+	modTime time.Time
+	offset  int64     // Start of the contents in a tar file
+	zipFile *zip.File // Member of a zip file
+}
+
+// archiveFormat detects the format of an archive from its file name
+func archiveFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return formatZip
+	case strings.HasSuffix(lower, ".tar"):
+		return formatTar
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return formatTarGz
+	}
+	return ""
+}
+
+// OpenArchive reads the table of contents of an archive. The archive stays
+// open until Close is called.
+func OpenArchive(ctx context.Context, archivePath string) (*Archive, error) {
+	a := &Archive{
+		Path:    archivePath,
+		format:  archiveFormat(archivePath),
+		entries: make(map[string]archiveEntry),
+		dirs:    make(map[string]string),
+	}
+	if a.format == "" {
+		return nil, fmt.Errorf("unsupported archive %s", archivePath)
+	}
+
+	if err := a.index(ctx); err != nil {
+		a.Close()
+		return nil, fmt.Errorf("failed to read archive %s: %v", archivePath, err)
+	}
+
+	// A language is a folder with XML files or a saved listing; invalid
+	// downloads quarantined by the fetcher are left out
+	for name := range a.entries {
+		base := path.Base(name)
+		if !strings.HasSuffix(base, ".xml") && base != "index.html" {
+			continue
+		}
+		folder := path.Dir(name)
+		code := path.Base(folder)
+		if folder == "." || code == manifest.QuarantineDir {
+			continue
+		}
+		if existing, ok := a.dirs[code]; ok && existing != folder {
+			a.Close()
+			return nil, fmt.Errorf("archive %s has two folders for %s: %s and %s", archivePath, code, existing, folder)
+		}
+		a.dirs[code] = folder
+	}
+
+	return a, nil
+}
+
+// Close closes the archive and drops the unpacked copy of a gzipped tar
+func (a *Archive) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	if a.unpacked != "" {
+		if rmErr := os.Remove(a.unpacked); rmErr != nil && err == nil {
+			err = rmErr
+		}
+		a.unpacked = ""
+	}
+	a.file = nil
+	return err
+}
+
+// Directories lists the language folders of the archive
+func (a *Archive) Directories(ctx context.Context) ([]models.Directory, error) {
+	codes := make([]string, 0, len(a.dirs))
+	for code := range a.dirs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	directories := make([]models.Directory, 0, len(codes))
+	for _, code := range codes {
+		directories = append(directories, parser.NewDirectory(code, a.Path+"#"+a.dirs[code]+"/"))
+	}
+	return directories, nil
+}
+
+// Files lists the XML files of a language folder
+func (a *Archive) Files(ctx context.Context, dir models.Directory) (Listing, error) {
+	if err := ctx.Err(); err != nil {
+		return Listing{}, err
+	}
+	folder, ok := a.dirs[dir.Code]
+	if !ok {
+		return Listing{}, fmt.Errorf("no folder for %s in %s", dir.Code, a.Path)
+	}
+
+	var names []string
+	for name := range a.entries {
+		if path.Dir(name) == folder && strings.HasSuffix(name, ".xml") {
+			names = append(names, path.Base(name))
+		}
+	}
+	sort.Strings(names)
+
+	index, err := a.readOptional(ctx, folder+"/index.html")
+	if err != nil {
+		return Listing{}, err
+	}
+	manifestData, err := a.readOptional(ctx, folder+"/"+manifest.FileName)
+	if err != nil {
+		return Listing{}, err
+	}
+
+	return mirrorListing(index, manifestData, names)
+}
+
+// Open extracts a file of a language folder. The modification time recorded in
+// the archive stands in for Last-Modified.
+func (a *Archive) Open(ctx context.Context, dir models.Directory, file models.File, req Request) (*File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	folder, ok := a.dirs[dir.Code]
+	if !ok {
+		return nil, retry.Permanent(fmt.Errorf("no folder for %s in %s", dir.Code, a.Path))
+	}
+	name := folder + "/" + file.Href
+	entry, ok := a.entries[name]
+	if !ok {
+		return nil, retry.Permanent(fmt.Errorf("%s not found in %s", name, a.Path))
+	}
+
+	lastModified := entry.modTime.UTC().Format(http.TimeFormat)
+	if req.LastModified != "" && req.LastModified == lastModified {
+		return &File{ReadCloser: io.NopCloser(strings.NewReader("")), NotModified: true, LastModified: lastModified}, nil
+	}
+
+	offset := min(req.Offset, entry.size)
+	if req.IfRange != "" && req.IfRange != lastModified {
+		offset = 0
+	}
+	rc, err := a.open(ctx, entry, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from %s: %v", name, a.Path, err)
+	}
+
+	return &File{ReadCloser: rc, Offset: offset, Size: entry.size - offset, LastModified: lastModified}, nil
+}
+
+// readOptional reads a small file from the archive, returning nil if it does not exist
+func (a *Archive) readOptional(ctx context.Context, name string) ([]byte, error) {
+	entry, ok := a.entries[name]
+	if !ok {
+		return nil, nil
+	}
+	rc, err := a.open(ctx, entry, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// open returns a reader for the contents of an entry from offset on. Tar
+// members are read in place; compressed zip members are decompressed and
+// skipped ahead to the offset.
+func (a *Archive) open(ctx context.Context, entry archiveEntry, offset int64) (io.ReadCloser, error) {
+	if entry.zipFile == nil {
+		return io.NopCloser(io.NewSectionReader(a.file, entry.offset+offset, entry.size-offset)), nil
+	}
+
+	rc, err := entry.zipFile.Open()
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	for offset > 0 {
+		if err := ctx.Err(); err != nil {
+			rc.Close()
+			return nil, err
+		}
+		n, err := io.CopyN(io.Discard, rc, min(offset, skipChunk))
+		offset -= n
+		if err != nil {
+			rc.Close()
+			return nil, fmt.Errorf("failed to skip to the offset: %v", err)
+		}
+	}
+	return rc, nil
+}
+
+// skipChunk is how much of a zip member is skipped between checks for cancellation
+const skipChunk = 1 << 20
+
+// index opens the archive and records its regular files
+func (a *Archive) index(ctx context.Context) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	a.file = f
+
+	switch a.format {
+	case formatZip:
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return err
+		}
+		for _, member := range zr.File {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !member.Mode().IsRegular() {
+				continue
+			}
+			a.entries[cleanName(member.Name)] = archiveEntry{size: int64(member.UncompressedSize64), modTime: member.Modified, zipFile: member}
+		}
+		return nil
+	case formatTarGz:
+		if err := a.unpack(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Reading straight from the file leaves it at the contents of each
+	// member, which can then be read in place
+	tr := tar.NewReader(a.file)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		offset, err := a.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		a.entries[cleanName(header.Name)] = archiveEntry{size: header.Size, modTime: header.ModTime, offset: offset}
+	}
+}
+
+// unpack decompresses a gzipped tar into a temporary file, which replaces the
+// archive as the file entries are read from. The temporary file is removed
+// right away where open files can be removed, and otherwise by Close.
+func (a *Archive) unpack(ctx context.Context) error {
+	archive := a.file
+	defer archive.Close()
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tmp, err := os.CreateTemp("", "lexin-archive-*.tar")
+	if err != nil {
+		return err
+	}
+	a.file = tmp
+	if err := os.Remove(tmp.Name()); err != nil {
+		a.unpacked = tmp.Name()
+	}
+
+	if _, err := io.Copy(tmp, ctxReader{ctx: ctx, r: gz}); err != nil {
+		return err
+	}
+	_, err = tmp.Seek(0, io.SeekStart)
+	return err
+}
+
+// ctxReader stops reading once ctx is cancelled
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// cleanName normalizes a member name, e.g. "./lexin/engelska/swe_eng.xml" to "lexin/engelska/swe_eng.xml"
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
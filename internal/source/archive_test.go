@@ -0,0 +1,276 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"getlexin-xml/internal/models"
+)
+
+// snapshotModTime is the modification time of every file in the test snapshots
+var snapshotModTime = time.Date(2024, 5, 17, 10, 30, 0, 0, time.UTC)
+
+const engelskaXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary><Article ID="1"><Lemma Value="hus"/></Article></Dictionary>
+`
+
+// snapshotFiles is a download directory as it would be archived, with the
+// languages at different depths and a quarantined download
+var snapshotFiles = map[string]string{
+	"lexin_downloads/sync-state.json":                 `{"languages":{}}`,
+	"lexin_downloads/engelska/swe_eng.xml":            engelskaXML,
+	"lexin_downloads/engelska/manifest.json":          `{"revision":"1234"}`,
+	"lexin_downloads/engelska/metadata.txt":           "Code: engelska\n",
+	"lexin_downloads/engelska/quarantine/swe_eng.xml": "<html>error</html>",
+	"lexin_downloads/more/arabiska/swe_ara.xml":       `<Dictionary/>`,
+	"lexin_downloads/more/arabiska/swe_ara_2.xml":     `<Dictionary/>`,
+	"lexin_downloads/quarantine/swe_deu.xml":          "<html>error</html>",
+}
+
+// snapshotNames returns the names of snapshotFiles in a stable order
+func snapshotNames() []string {
+	names := make([]string, 0, len(snapshotFiles))
+	for name := range snapshotFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeZip(t *testing.T, path string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	if _, err := zw.Create("lexin_downloads/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range snapshotNames() {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: snapshotModTime})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, snapshotFiles[name])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTar(t *testing.T, path string, gzipped bool) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if gzipped {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: "./lexin_downloads/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: snapshotModTime}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range snapshotNames() {
+		content := snapshotFiles[name]
+		header := &tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content)), ModTime: snapshotModTime}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, content)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	tests := []struct {
+		file  string
+		write func(t *testing.T, path string)
+	}{
+		{"snapshot.zip", writeZip},
+		{"snapshot.tar", func(t *testing.T, path string) { writeTar(t, path, false) }},
+		{"snapshot.tar.gz", func(t *testing.T, path string) { writeTar(t, path, true) }},
+		{"snapshot.tgz", func(t *testing.T, path string) { writeTar(t, path, true) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			tt.write(t, path)
+
+			src, err := New(context.Background(), path, nil)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if _, ok := src.(*Archive); !ok {
+				t.Fatalf("New returned %T, want *Archive", src)
+			}
+
+			testSnapshotSource(t, src)
+
+			// A cancelled run reads nothing more from the archive
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			dir := models.Directory{Code: "engelska"}
+			if _, err := src.Open(ctx, dir, models.File{Name: "swe_eng.xml", Href: "swe_eng.xml"}, Request{}); err != context.Canceled {
+				t.Errorf("Open with a cancelled context = %v, want %v", err, context.Canceled)
+			}
+			if _, err := src.Files(ctx, dir); err != context.Canceled {
+				t.Errorf("Files with a cancelled context = %v, want %v", err, context.Canceled)
+			}
+
+			if err := src.(*Archive).Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		})
+	}
+}
+
+// testSnapshotSource checks a source serving the files of snapshotFiles
+func testSnapshotSource(t *testing.T, src Source) {
+	t.Helper()
+	ctx := context.Background()
+
+	// Languages are found at any depth, quarantine folders are not languages
+	directories, err := src.Directories(ctx)
+	if err != nil {
+		t.Fatalf("Directories: %v", err)
+	}
+	var codes []string
+	byCode := make(map[string]models.Directory)
+	for _, dir := range directories {
+		codes = append(codes, dir.Code)
+		byCode[dir.Code] = dir
+	}
+	if len(codes) != 2 || codes[0] != "arabiska" || codes[1] != "engelska" {
+		t.Fatalf("directories = %v, want [arabiska engelska]", codes)
+	}
+
+	// The revision comes from the manifest, quarantined files are not listed
+	listing, err := src.Files(ctx, byCode["engelska"])
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if listing.Revision != "1234" {
+		t.Errorf("revision = %q, want %q", listing.Revision, "1234")
+	}
+	if got := fileNames(listing.Files); len(got) != 1 || got[0] != "swe_eng.xml" {
+		t.Errorf("engelska files = %v, want [swe_eng.xml]", got)
+	}
+
+	listing, err = src.Files(ctx, byCode["arabiska"])
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if got := fileNames(listing.Files); len(got) != 2 || got[0] != "swe_ara.xml" || got[1] != "swe_ara_2.xml" {
+		t.Errorf("arabiska files = %v, want [swe_ara.xml swe_ara_2.xml]", got)
+	}
+	if listing.Revision != "" {
+		t.Errorf("revision without manifest = %q, want none", listing.Revision)
+	}
+
+	// Files are read from an offset, which is clamped to the size
+	file := models.File{Name: "swe_eng.xml", Href: "swe_eng.xml"}
+	size := int64(len(engelskaXML))
+	for _, offset := range []int64{0, 10, size + 5} {
+		f, err := src.Open(ctx, byCode["engelska"], file, Request{Offset: offset})
+		if err != nil {
+			t.Fatalf("Open at %d: %v", offset, err)
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("reading at %d: %v", offset, err)
+		}
+
+		start := min(offset, size)
+		if string(content) != engelskaXML[start:] {
+			t.Errorf("content at %d = %q, want %q", offset, content, engelskaXML[start:])
+		}
+		if f.Offset != start || f.Size != size-start {
+			t.Errorf("Open at %d: offset %d and size %d, want %d and %d", offset, f.Offset, f.Size, start, size-start)
+		}
+	}
+
+	// The modification time stands in for Last-Modified
+	lastModified := snapshotModTime.Format(http.TimeFormat)
+	f, err := src.Open(ctx, byCode["engelska"], file, Request{LastModified: lastModified})
+	if err != nil {
+		t.Fatalf("conditional Open: %v", err)
+	}
+	f.Close()
+	if !f.NotModified {
+		t.Errorf("file with the same modification time is not reported as not modified")
+	}
+
+	earlier := snapshotModTime.Add(-time.Hour).Format(http.TimeFormat)
+	f, err = src.Open(ctx, byCode["engelska"], file, Request{LastModified: earlier})
+	if err != nil {
+		t.Fatalf("conditional Open: %v", err)
+	}
+	content, _ := io.ReadAll(f)
+	f.Close()
+	if f.NotModified || string(content) != engelskaXML {
+		t.Errorf("changed file: NotModified %v and content %q, want the whole file", f.NotModified, content)
+	}
+	if f.LastModified != lastModified {
+		t.Errorf("LastModified = %q, want %q", f.LastModified, lastModified)
+	}
+
+	// Missing files are reported as errors
+	if _, err := src.Open(ctx, byCode["engelska"], models.File{Name: "swe_fin.xml", Href: "swe_fin.xml"}, Request{}); err == nil {
+		t.Error("Open succeeded for a missing file")
+	}
+}
+
+func fileNames(files []models.File) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names
+}
+
+func TestArchiveRejectsDuplicateLanguageFolders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"a/engelska/swe_eng.xml", "b/engelska/swe_eng.xml"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, engelskaXML)
+	}
+	zw.Close()
+	f.Close()
+
+	if _, err := OpenArchive(context.Background(), path); err == nil {
+		t.Error("OpenArchive accepted two folders for the same language")
+	}
+}
@@ -0,0 +1,140 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"getlexin-xml/internal/manifest"
+	"getlexin-xml/internal/models"
+	"getlexin-xml/internal/parser"
+	"getlexin-xml/internal/retry"
+)
+
+// Dir reads a mirror on the local filesystem with one subdirectory per
+// language, such as the output directory of an earlier download
+type Dir struct {
+	Root string
+}
+
+// NewDir creates a source for the mirror in root
+func NewDir(root string) *Dir {
+	return &Dir{Root: root}
+}
+
+// Directories lists the subdirectories that contain XML files
+func (s *Dir) Directories(ctx context.Context) ([]models.Directory, error) {
+	entries, err := os.ReadDir(s.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror: %v", err)
+	}
+
+	var directories []models.Directory
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == manifest.QuarantineDir {
+			continue
+		}
+		path := filepath.Join(s.Root, entry.Name())
+		names, err := xmlFileNames(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 && !exists(filepath.Join(path, "index.html")) {
+			continue
+		}
+		directories = append(directories, parser.NewDirectory(entry.Name(), path+string(filepath.Separator)))
+	}
+
+	return directories, nil
+}
+
+// Files lists the XML files of a language directory
+func (s *Dir) Files(ctx context.Context, dir models.Directory) (Listing, error) {
+	path := filepath.Join(s.Root, dir.Code)
+	names, err := xmlFileNames(path)
+	if err != nil {
+		return Listing{}, err
+	}
+
+	index, err := readOptional(filepath.Join(path, "index.html"))
+	if err != nil {
+		return Listing{}, err
+	}
+	manifestData, err := readOptional(filepath.Join(path, manifest.FileName))
+	if err != nil {
+		return Listing{}, err
+	}
+
+	return mirrorListing(index, manifestData, names)
+}
+
+// Open opens a file of the mirror. The modification time stands in for
+// Last-Modified, so unchanged files are reported as not modified.
+func (s *Dir) Open(ctx context.Context, dir models.Directory, file models.File, req Request) (*File, error) {
+	path := filepath.Join(s.Root, dir.Code, filepath.FromSlash(file.Href))
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, retry.Permanent(err)
+	}
+
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+	if req.LastModified != "" && req.LastModified == lastModified {
+		f.Close()
+		return &File{ReadCloser: io.NopCloser(strings.NewReader("")), NotModified: true, LastModified: lastModified}, nil
+	}
+
+	offset := min(req.Offset, info.Size())
+	if req.IfRange != "" && req.IfRange != lastModified {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, retry.Permanent(err)
+	}
+
+	return &File{ReadCloser: f, Offset: offset, Size: info.Size() - offset, LastModified: lastModified}, nil
+}
+
+// xmlFileNames returns the names of the XML files in a directory, sorted
+func xmlFileNames(path string) ([]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".xml") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readOptional reads a file, returning nil if it does not exist
+func readOptional(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
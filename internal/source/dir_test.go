@@ -0,0 +1,37 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	// A mirror has its languages right below the root
+	root := t.TempDir()
+	for name, content := range snapshotFiles {
+		name = strings.Replace(name, "/more/", "/", 1)
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, snapshotModTime, snapshotModTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := New(context.Background(), filepath.Join(root, "lexin_downloads"), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, ok := src.(*Dir); !ok {
+		t.Fatalf("New returned %T, want *Dir", src)
+	}
+
+	testSnapshotSource(t, src)
+}
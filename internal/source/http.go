@@ -0,0 +1,158 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"getlexin-xml/internal/models"
+	"getlexin-xml/internal/parser"
+	"getlexin-xml/internal/ratelimit"
+	"getlexin-xml/internal/retry"
+)
+
+// HTTP reads the SVN-style XML listings served by the ISOF web server
+type HTTP struct {
+	BaseURL string             // URL of the top-level listing, ending in a slash
+	Client  *http.Client       // Client for every request
+	Retry   retry.Policy       // Used for the listings; Open makes a single attempt
+	Limiter *ratelimit.Limiter // Waited on before every listing attempt (optional)
+}
+
+// NewHTTP creates a source for the listing at baseURL; a nil client means http.DefaultClient
+func NewHTTP(baseURL string, client *http.Client) *HTTP {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTP{BaseURL: baseURL, Client: client, Retry: retry.DefaultPolicy()}
+}
+
+// Directories fetches the top-level listing
+func (s *HTTP) Directories(ctx context.Context) ([]models.Directory, error) {
+	content, err := s.getListing(ctx, s.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %v", err)
+	}
+
+	return parser.ParseDirectories(string(content), s.BaseURL)
+}
+
+// Files fetches the listing of a language directory
+func (s *HTTP) Files(ctx context.Context, dir models.Directory) (Listing, error) {
+	content, err := s.getListing(ctx, dir.URL)
+	if err != nil {
+		return Listing{}, fmt.Errorf("failed to fetch directory: %v", err)
+	}
+
+	index, err := parser.ParseIndex(string(content))
+	if err != nil {
+		return Listing{}, fmt.Errorf("failed to parse directory contents: %v", err)
+	}
+
+	return Listing{Revision: index.Rev, Files: parser.XMLFiles(index), Index: content}, nil
+}
+
+// getListing fetches a listing with retries, waiting for the rate limit before every attempt
+func (s *HTTP) getListing(ctx context.Context, url string) ([]byte, error) {
+	var content []byte
+	err := s.Retry.Do(ctx, func() error {
+		if err := s.Limiter.WaitRequest(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return retry.NewStatusError(resp)
+		}
+
+		content, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read listing: %v", err)
+		}
+		return nil
+	})
+	return content, err
+}
+
+// Open requests a file, resuming with a Range request (guarded by If-Range) and
+// revalidating with If-None-Match/If-Modified-Since as asked. Unexpected statuses are returned as
+// *retry.StatusError.
+func (s *HTTP) Open(ctx context.Context, dir models.Directory, file models.File, req Request) (*File, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, dir.URL+file.Href, nil)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	if req.Offset > 0 {
+		httpReq.Header.Set("Range", fmt.Sprintf("bytes=%d-", req.Offset))
+		if req.IfRange != "" {
+			httpReq.Header.Set("If-Range", req.IfRange)
+		}
+	}
+	if req.ETag != "" {
+		httpReq.Header.Set("If-None-Match", req.ETag)
+	}
+	if req.LastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", req.LastModified)
+	}
+
+	resp, err := s.Client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		ReadCloser:   resp.Body,
+		Size:         resp.ContentLength,
+		Status:       resp.StatusCode,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Sent from the start, whether or not a Range was asked for
+	case http.StatusNotModified:
+		f.NotModified = true
+	case http.StatusPartialContent:
+		start, err := parseContentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil {
+			start = -1
+		}
+		f.Offset = start
+	default:
+		resp.Body.Close()
+		return nil, retry.NewStatusError(resp)
+	}
+
+	return f, nil
+}
+
+// parseContentRangeStart returns the first byte position of a "bytes start-end/size" header
+func parseContentRangeStart(header string) (int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, fmt.Errorf("unsupported Content-Range %q", header)
+	}
+
+	dash := strings.Index(spec, "-")
+	if dash == -1 {
+		return 0, fmt.Errorf("malformed Content-Range %q", header)
+	}
+
+	return strconv.ParseInt(spec[:dash], 10, 64)
+}
@@ -0,0 +1,64 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"getlexin-xml/internal/models"
+	"getlexin-xml/internal/ratelimit"
+	"getlexin-xml/internal/retry"
+)
+
+const testListing = `<?xml version="1.0"?>
+<svn version="1.14"><index rev="4711" path="/lexin/engelska" base="lexin">
+  <updir href="../"/>
+  <file name="swe_eng.xml" href="swe_eng.xml"/>
+  <file name="readme.txt" href="readme.txt"/>
+</index></svn>
+`
+
+func TestHTTPListingRetriesWaitForRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail twice, then send the listing
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, testListing)
+	}))
+	defer srv.Close()
+
+	src := NewHTTP(srv.URL, srv.Client())
+	src.Retry = retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	src.Limiter = ratelimit.New(10, 0) // One request every 100ms
+
+	start := time.Now()
+	listing, err := src.Files(context.Background(), models.Directory{Code: "engelska", URL: srv.URL + "/engelska/"})
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+	// The first request uses the token in the bucket, both retries wait for a new one
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("three listing attempts took %v, want at least 200ms", elapsed)
+	}
+
+	if listing.Revision != "4711" {
+		t.Errorf("revision = %q, want %q", listing.Revision, "4711")
+	}
+	if len(listing.Files) != 1 || listing.Files[0].Name != "swe_eng.xml" {
+		t.Errorf("files = %+v, want only swe_eng.xml", listing.Files)
+	}
+	if string(listing.Index) != testListing {
+		t.Errorf("index = %q, want the raw listing", listing.Index)
+	}
+}
@@ -0,0 +1,110 @@
+// Package source abstracts where the dictionaries are downloaded from: the
+// ISOF web server, a local mirror of it or an archived snapshot.
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"getlexin-xml/internal/models"
+	"getlexin-xml/internal/parser"
+)
+
+// DefaultLocation is the Lexin directory on the ISOF server
+const DefaultLocation = "https://sprakresurser.isof.se/lexin/"
+
+// Source lists the language directories and their files and opens the files
+type Source interface {
+	// Directories lists the language directories
+	Directories(ctx context.Context) ([]models.Directory, error)
+
+	// Files lists the XML files of a language directory together with its revision
+	Files(ctx context.Context, dir models.Directory) (Listing, error)
+
+	// Open makes a single attempt at opening a file of a language directory.
+	// Errors that are not worth retrying are marked with retry.Permanent.
+	Open(ctx context.Context, dir models.Directory, file models.File, req Request) (*File, error)
+}
+
+// Listing is the content of a language directory
+type Listing struct {
+	Revision string        // SVN revision of the directory, empty if unknown
+	Files    []models.File // XML files in the directory
+	Index    []byte        // Raw SVN listing, saved as index.html; nil if the source has none
+}
+
+// Request asks for a file, optionally from an offset or only if it has changed
+type Request struct {
+	Offset       int64  // Resume at this byte
+	IfRange      string // Validator the partial file was started with; the whole file is sent if it no longer matches
+	ETag         string // Validators of the local copy, for a conditional request
+	LastModified string
+}
+
+// File is an opened file of a source
+type File struct {
+	io.ReadCloser
+	Offset       int64 // Byte the content starts at; 0 if the source ignored the requested offset, -1 if unknown
+	Size         int64 // Length of the content, -1 if unknown
+	NotModified  bool  // The file matches the validators of the request; the content is empty
+	Status       int   // HTTP status of the response, zero for other sources
+	ETag         string
+	LastModified string
+}
+
+// New picks the source for a location: an http(s) URL of an SVN listing, read
+// with client, a directory holding a mirror, or a .zip, .tar, .tar.gz or .tgz snapshot.
+// Archives are indexed right away, which ctx can cancel; they implement io.Closer.
+func New(ctx context.Context, location string, client *http.Client) (Source, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return NewHTTP(location, client), nil
+	}
+
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported source %q: %v", location, err)
+	}
+	if info.IsDir() {
+		return NewDir(location), nil
+	}
+	if archiveFormat(location) != "" {
+		return OpenArchive(ctx, location)
+	}
+
+	return nil, fmt.Errorf("unsupported source %q: expected a URL, a directory or a .zip, .tar, .tar.gz or .tgz archive", location)
+}
+
+// mirrorListing builds the listing of a mirrored language directory from the
+// files it contains. A saved SVN listing (index.html) is preferred, then the
+// revision recorded in manifest.json, and otherwise the XML files found are listed
+// without a revision.
+func mirrorListing(index, manifestData []byte, xmlNames []string) (Listing, error) {
+	if index != nil {
+		svnIndex, err := parser.ParseIndex(string(index))
+		if err != nil {
+			return Listing{}, fmt.Errorf("failed to parse index.html: %v", err)
+		}
+		return Listing{Revision: svnIndex.Rev, Files: parser.XMLFiles(svnIndex), Index: index}, nil
+	}
+
+	var listing Listing
+	if manifestData != nil {
+		var m struct {
+			Revision string `json:"revision"`
+		}
+		if err := json.Unmarshal(manifestData, &m); err != nil {
+			return Listing{}, fmt.Errorf("failed to parse manifest.json: %v", err)
+		}
+		listing.Revision = m.Revision
+	}
+	for _, name := range xmlNames {
+		listing.Files = append(listing.Files, models.File{Name: name, Href: name})
+	}
+
+	return listing, nil
+}
@@ -0,0 +1,500 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+
+	"getlexin-xml/internal/models"
+)
+
+// Messages fed into the model while downloading
+type (
+	progressMsg      models.ProgressEvent
+	resultMsg        models.DownloadResult
+	downloadsDoneMsg struct{}
+	cancelledMsg     struct{} // The context given to NewModel is done
+)
+
+// Download states of a language on the dashboard
+type languageStatus int
+
+const (
+	statusQueued languageStatus = iota
+	statusDownloading
+	statusCompleted
+	statusUpToDate
+	statusPartial
+	statusFailed
+	statusCancelling
+)
+
+func (s languageStatus) String() string {
+	switch s {
+	case statusQueued:
+		return "queued"
+	case statusDownloading:
+		return "downloading"
+	case statusCompleted:
+		return "completed"
+	case statusUpToDate:
+		return "up to date"
+	case statusPartial:
+		return "partial"
+	case statusFailed:
+		return "failed"
+	case statusCancelling:
+		return "cancelling"
+	}
+	return "unknown"
+}
+
+// languageProgress is the dashboard row of one language
+type languageProgress struct {
+	directory  models.Directory
+	status     languageStatus
+	started    time.Time
+	inFlight   map[string]models.ProgressEvent // Latest event of each file being downloaded, by name
+	fileCount  int                             // Number of XML files in the directory
+	filesDone  int                             // Files finished successfully
+	fileErrors int                             // Files that failed
+	bytesDone  int64                           // Bytes of finished files
+	err        error
+}
+
+// finished reports whether the language has a final result
+func (l *languageProgress) finished() bool {
+	switch l.status {
+	case statusCompleted, statusUpToDate, statusPartial, statusFailed:
+		return true
+	}
+	return false
+}
+
+// fraction estimates how much of the language is done, from 0 to 1
+func (l *languageProgress) fraction() float64 {
+	if l.finished() {
+		return 1
+	}
+	if l.fileCount == 0 {
+		return 0
+	}
+
+	done := float64(l.filesDone + l.fileErrors)
+	for _, event := range l.inFlight {
+		if event.ContentLength > 0 {
+			done += float64(event.BytesRead) / float64(event.ContentLength)
+		}
+	}
+	return min(done/float64(l.fileCount), 1)
+}
+
+// current returns the bytes of the files in flight so far
+func (l *languageProgress) current() int64 {
+	var bytes int64
+	for _, event := range l.inFlight {
+		bytes += event.BytesRead
+	}
+	return bytes
+}
+
+// throughput returns the combined bytes per second of the files in flight
+func (l *languageProgress) throughput() float64 {
+	var throughput float64
+	for _, event := range l.inFlight {
+		throughput += event.Throughput
+	}
+	return throughput
+}
+
+// files lists the names of the files in flight
+func (l *languageProgress) files() string {
+	names := make([]string, 0, len(l.inFlight))
+	for name := range l.inFlight {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// eta extrapolates the remaining time from the elapsed time and the fraction done
+func (l *languageProgress) eta() (time.Duration, bool) {
+	fraction := l.fraction()
+	if l.started.IsZero() || fraction <= 0 || fraction >= 1 {
+		return 0, false
+	}
+
+	elapsed := time.Since(l.started)
+	return time.Duration(float64(elapsed)/fraction) - elapsed, true
+}
+
+// Dashboard keybindings
+type dashboardKeyMap struct {
+	Up        key.Binding
+	Down      key.Binding
+	Cancel    key.Binding
+	CancelAll key.Binding
+	Exit      key.Binding
+}
+
+func (k dashboardKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Up, k.Down, k.Cancel, k.CancelAll, k.Exit}
+}
+
+func (k dashboardKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.ShortHelp()}
+}
+
+var dashboardKeys = dashboardKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "move up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "move down"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("x", "delete"),
+		key.WithHelp("x", "cancel language"),
+	),
+	CancelAll: key.NewBinding(
+		key.WithKeys("q", "esc", "ctrl+c"),
+		key.WithHelp("q", "cancel all and exit"),
+	),
+	Exit: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("enter", "exit when done"),
+	),
+}
+
+// startDownloads switches the model to the dashboard and starts the download manager
+func (m Model) startDownloads(selected []models.Directory) (Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancel = cancel
+	m.ShowDownloads = true
+	m.startTime = time.Now()
+
+	m.languages = make([]*languageProgress, len(selected))
+	m.languageIndex = make(map[string]int, len(selected))
+	for i, dir := range selected {
+		m.languages[i] = &languageProgress{directory: dir, status: statusQueued}
+		m.languageIndex[dir.Code] = i
+	}
+
+	// Forward progress callbacks into the Bubble Tea event loop.
+	// Intermediate events are dropped when the UI falls behind; final events are not.
+	events := make(chan models.ProgressEvent, 256)
+	m.events = events
+	m.downloader.Progress = func(event models.ProgressEvent) {
+		if event.Done {
+			events <- event
+			return
+		}
+		select {
+		case events <- event:
+		default:
+		}
+	}
+
+	m.downloader.StartDownloads(ctx, selected)
+
+	return m, tea.Batch(
+		m.spinner.Tick,
+		waitForProgress(m.events),
+		waitForResult(m.downloader.Results, m.events),
+	)
+}
+
+// waitForProgress delivers the next progress event
+func waitForProgress(events <-chan models.ProgressEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
+			return nil
+		}
+		return progressMsg(event)
+	}
+}
+
+// waitForCancel reports when ctx is done, for example on SIGINT or SIGTERM
+func waitForCancel(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		<-ctx.Done()
+		return cancelledMsg{}
+	}
+}
+
+// waitForResult delivers the next language result, closing events once all are in
+func waitForResult(results <-chan models.DownloadResult, events chan models.ProgressEvent) tea.Cmd {
+	return func() tea.Msg {
+		result, ok := <-results
+		if !ok {
+			// All download goroutines have returned, so no more progress callbacks can happen
+			close(events)
+			return downloadsDoneMsg{}
+		}
+		return resultMsg(result)
+	}
+}
+
+// updateDashboard handles messages while downloads are shown
+func (m Model) updateDashboard(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case m.downloadsDone && key.Matches(msg, dashboardKeys.Exit):
+			return m, tea.Quit
+
+		case key.Matches(msg, dashboardKeys.Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+
+		case key.Matches(msg, dashboardKeys.Down):
+			if m.cursor < len(m.languages)-1 {
+				m.cursor++
+			}
+
+		case key.Matches(msg, dashboardKeys.Cancel):
+			if m.cursor < len(m.languages) {
+				lang := m.languages[m.cursor]
+				if !lang.finished() {
+					lang.status = statusCancelling
+					m.downloader.Cancel(lang.directory.Code)
+				}
+			}
+
+		case key.Matches(msg, dashboardKeys.CancelAll):
+			for _, lang := range m.languages {
+				if !lang.finished() {
+					lang.status = statusCancelling
+				}
+			}
+			m.cancel()
+			if m.downloadsDone {
+				return m, tea.Quit
+			}
+			m.exitWhenDone = true
+		}
+		return m, nil
+
+	case cancelledMsg:
+		// The downloads stop on their own since their context derives from the
+		// cancelled one; wait for the results so the summary can be printed
+		for _, lang := range m.languages {
+			if !lang.finished() {
+				lang.status = statusCancelling
+			}
+		}
+		m.cancel()
+		if m.downloadsDone {
+			return m, tea.Quit
+		}
+		m.exitWhenDone = true
+		return m, nil
+
+	case progressMsg:
+		if i, ok := m.languageIndex[msg.Directory]; ok {
+			m.languages[i].apply(models.ProgressEvent(msg))
+		}
+		return m, waitForProgress(m.events)
+
+	case resultMsg:
+		result := models.DownloadResult(msg)
+		m.results = append(m.results, result)
+		if i, ok := m.languageIndex[result.Directory.Code]; ok {
+			m.languages[i].finish(result)
+		}
+		return m, waitForResult(m.downloader.Results, m.events)
+
+	case downloadsDoneMsg:
+		m.downloadsDone = true
+		m.elapsed = time.Since(m.startTime)
+		m.cancel()
+		if m.exitWhenDone {
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case spinner.TickMsg:
+		if m.downloadsDone {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// apply updates the row from a progress event
+func (l *languageProgress) apply(event models.ProgressEvent) {
+	// Events can trail the final result since they arrive on a separate channel
+	if l.finished() {
+		return
+	}
+
+	if l.status == statusQueued {
+		l.status = statusDownloading
+		l.started = time.Now()
+	}
+
+	l.fileCount = event.FileCount
+
+	if event.Done {
+		if event.Error != nil {
+			l.fileErrors++
+			l.err = event.Error
+		} else {
+			l.filesDone++
+			l.bytesDone += event.BytesRead
+		}
+		delete(l.inFlight, event.File)
+		return
+	}
+
+	if l.inFlight == nil {
+		l.inFlight = make(map[string]models.ProgressEvent)
+	}
+	l.inFlight[event.File] = event
+}
+
+// finish records the final result of the language
+func (l *languageProgress) finish(result models.DownloadResult) {
+	switch {
+	case result.UpToDate:
+		l.status = statusUpToDate
+	case result.Success:
+		l.status = statusCompleted
+	case result.Partial:
+		l.status = statusPartial
+	default:
+		l.status = statusFailed
+	}
+
+	l.bytesDone = result.TotalBytes
+	l.filesDone = result.FileCount + result.NotModifiedCount
+	l.fileErrors = len(result.FailedFiles())
+	if result.Error != nil {
+		l.err = result.Error
+	}
+	l.inFlight = nil
+}
+
+// dashboardView renders one row per language
+func (m Model) dashboardView() string {
+	var s strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#0066CC")).
+		Padding(0, 1)
+	cursorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#EE6FF8")).
+		Bold(true)
+	descStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#777777"))
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FF5555"))
+	okStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#10a010"))
+
+	s.WriteString("\n")
+	s.WriteString(titleStyle.Render(fmt.Sprintf("Downloading %d Lexin Dictionaries", len(m.languages))))
+	s.WriteString("\n\n")
+
+	finished := 0
+	for i, lang := range m.languages {
+		if lang.finished() {
+			finished++
+		}
+
+		cursor := "  "
+		if i == m.cursor {
+			cursor = cursorStyle.Render("> ")
+		}
+
+		// Status indicator
+		var indicator string
+		switch lang.status {
+		case statusCompleted, statusUpToDate:
+			indicator = okStyle.Render("✓")
+		case statusPartial, statusFailed:
+			indicator = errorStyle.Render("✗")
+		case statusQueued:
+			indicator = descStyle.Render("·")
+		default:
+			indicator = m.spinner.View()
+		}
+
+		// Files and bytes
+		files := "-"
+		if lang.fileCount > 0 || lang.finished() {
+			files = fmt.Sprintf("%d/%d files", lang.filesDone, max(lang.fileCount, lang.filesDone+lang.fileErrors))
+		}
+		bytes := humanize.Bytes(uint64(lang.bytesDone + lang.current()))
+
+		line := fmt.Sprintf("%s%s %-15s %s %-12s %-14s %-10s",
+			cursor, indicator, lang.directory.Code, m.bar.ViewAs(lang.fraction()), lang.status, files, bytes)
+
+		// Throughput and ETA while downloading
+		if lang.status == statusDownloading {
+			if throughput := lang.throughput(); throughput > 0 {
+				line += fmt.Sprintf(" %s/s", humanize.Bytes(uint64(throughput)))
+			}
+			if eta, ok := lang.eta(); ok {
+				line += fmt.Sprintf(" ETA %s", eta.Round(time.Second))
+			}
+		}
+		s.WriteString(line)
+		s.WriteString("\n")
+
+		// Second line with the current file or the last error
+		detail := ""
+		if lang.err != nil {
+			detail = errorStyle.Render(fmt.Sprintf("%d errors, last: %v", lang.fileErrors, lang.err))
+			if lang.fileErrors == 0 {
+				detail = errorStyle.Render(lang.err.Error())
+			}
+		} else if files := lang.files(); files != "" {
+			detail = descStyle.Render(files)
+		}
+		s.WriteString("     " + detail + "\n")
+	}
+
+	s.WriteString("\n")
+	if m.downloadsDone {
+		s.WriteString(okStyle.Render(fmt.Sprintf("All downloads finished in %s • Press Enter to exit", m.elapsed.Round(time.Second))))
+	} else {
+		s.WriteString(fmt.Sprintf("%d/%d languages finished • Elapsed %s", finished, len(m.languages), time.Since(m.startTime).Round(time.Second)))
+	}
+	s.WriteString("\n\n")
+
+	helpView := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#666666")).
+		Render(m.help.View(dashboardKeys))
+	s.WriteString(helpView)
+
+	return s.String()
+}
+
+// newDashboardWidgets creates the spinner and progress bar used on the dashboard
+func newDashboardWidgets() (spinner.Model, progress.Model) {
+	sp := spinner.New(
+		spinner.WithSpinner(spinner.Dot),
+		spinner.WithStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("#EE6FF8"))),
+	)
+	bar := progress.New(progress.WithDefaultGradient(), progress.WithWidth(24))
+	return sp, bar
+}
@@ -1,16 +1,21 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"getlexin-xml/internal/fetcher"
 	"getlexin-xml/internal/models"
 )
 
@@ -97,11 +102,25 @@ type Model struct {
 	help          help.Model
 	directories   []models.Directory
 	allSelected   bool
-	baseURL       string
-	outputDir     string
-	concurrency   int
+	location      string // Where the dictionaries are downloaded from
 	quitting      bool
 	ShowDownloads bool // Exported to be accessible from main
+
+	// Download dashboard state
+	ctx           context.Context
+	cancel        context.CancelFunc
+	downloader    *fetcher.DownloadManager
+	events        chan models.ProgressEvent
+	spinner       spinner.Model
+	bar           progress.Model
+	languages     []*languageProgress
+	languageIndex map[string]int
+	cursor        int
+	results       []models.DownloadResult
+	downloadsDone bool
+	exitWhenDone  bool // Quit as soon as the cancelled downloads have wound down
+	startTime     time.Time
+	elapsed       time.Duration
 }
 
 // Custom delegate for the list that shows checkboxes
@@ -162,8 +181,8 @@ func (d customItemDelegate) Render(w io.Writer, m list.Model, index int, listIte
 	fmt.Fprintf(w, "%s%s\n  %s", cursor, title, desc)
 }
 
-// NewModel creates a new TUI model
-func NewModel(directories []models.Directory, baseURL, outputDir string, concurrency int) Model {
+// NewModel creates a new TUI model; selected languages are downloaded with downloader until ctx is done
+func NewModel(ctx context.Context, directories []models.Directory, location string, downloader *fetcher.DownloadManager) Model {
 	// Add an "All Languages" option at the top
 	allOption := models.Directory{
 		Code:        "all",
@@ -205,26 +224,42 @@ func NewModel(directories []models.Directory, baseURL, outputDir string, concurr
 	// Help model
 	h := help.New()
 
+	// Dashboard widgets
+	sp, bar := newDashboardWidgets()
+
 	return Model{
 		list:          l,
 		keys:          keys,
 		help:          h,
 		directories:   allDirs,
 		allSelected:   false,
-		baseURL:       baseURL,
-		outputDir:     outputDir,
-		concurrency:   concurrency,
+		location:      location,
 		quitting:      false,
 		ShowDownloads: false,
+		ctx:           ctx,
+		cancel:        func() {},
+		downloader:    downloader,
+		spinner:       sp,
+		bar:           bar,
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	return waitForCancel(m.ctx)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Once downloads have started the dashboard handles everything
+	if m.ShowDownloads {
+		return m.updateDashboard(msg)
+	}
+
 	switch msg := msg.(type) {
+	case cancelledMsg:
+		// Interrupted before anything was downloaded
+		m.quitting = true
+		return m, tea.Quit
+
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(msg, m.keys.Quit):
@@ -324,17 +359,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			if len(selectedDirs) > 0 {
-				m.ShowDownloads = true
-				// Return selected directories to start download
-				return m, func() tea.Msg {
-					return selectedDirs
-				}
+				// Switch to the download dashboard
+				return m.startDownloads(selectedDirs)
 			}
 		}
-
-	case []models.Directory:
-		// This is the return message from the download command
-		return m, tea.Quit
 	}
 
 	var cmd tea.Cmd
@@ -348,7 +376,7 @@ func (m Model) View() string {
 	}
 
 	if m.ShowDownloads {
-		return "Starting downloads...\n\nPress Ctrl+C to exit the program"
+		return m.dashboardView()
 	}
 
 	// Count selected items (excluding "All Languages" option)
@@ -386,6 +414,16 @@ func (m Model) View() string {
 	return s.String()
 }
 
+// Results returns the results of the languages downloaded from the dashboard
+func (m Model) Results() []models.DownloadResult {
+	return m.results
+}
+
+// Elapsed returns how long the downloads took
+func (m Model) Elapsed() time.Duration {
+	return m.elapsed
+}
+
 // GetSelectedDirectories returns the directories selected by the user
 func (m *Model) GetSelectedDirectories(originalDirs []models.Directory) []models.Directory {
 	var selectedDirs []models.Directory
@@ -0,0 +1,269 @@
+// Package lexin decodes Lexin dictionary XML files into Go types.
+//
+// A Lexin file is a Dictionary of Articles. Every Article holds one or more
+// Lemmas (Swedish headwords with their word class, phonetics and inflections),
+// and every Lemma holds one or more Lexemes (senses) with definitions,
+// translations, examples, idioms, compounds and cross-references.
+package lexin
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Dictionary is the root element of a Lexin XML file
+type Dictionary struct {
+	XMLName  xml.Name   `xml:"Dictionary"`
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Articles []Article  `xml:"Article"`
+}
+
+// Article is a dictionary entry, grouping the variants of a headword
+type Article struct {
+	ID     string  `xml:"ID,attr" json:"id"`
+	Lemmas []Lemma `xml:"Lemma" json:"lemmas,omitempty"`
+}
+
+// Lemma is a Swedish headword
+type Lemma struct {
+	ID          string       `xml:"ID,attr" json:"id"`
+	Value       string       `xml:"Value,attr" json:"value"`                   // The headword itself
+	WordClass   string       `xml:"Type,attr" json:"word_class,omitempty"`     // Word class, e.g. "subst.", "verb", "adj."
+	Hyphenate   string       `xml:"Hyphenate,attr" json:"hyphenate,omitempty"` // Syllable breaks marked with "|"
+	Rank        string       `xml:"Rank,attr" json:"rank,omitempty"`           // Frequency rank, lower is more common
+	Variant     string       `xml:"Variant,attr" json:"variant,omitempty"`
+	VariantID   string       `xml:"VariantID,attr" json:"variant_id,omitempty"`
+	Phonetics   []Phonetic   `xml:"Phonetic" json:"phonetics,omitempty"`
+	Inflections []Inflection `xml:"Inflection" json:"inflections,omitempty"`
+	Lexemes     []Lexeme     `xml:"Lexeme" json:"lexemes,omitempty"`
+	References  []Reference  `xml:"Reference" json:"references,omitempty"`
+	Comment     string       `xml:"Comment" json:"comment,omitempty"`
+}
+
+// Phonetic is a pronunciation, optionally with an audio file name
+type Phonetic struct {
+	File  string `xml:"File,attr" json:"file,omitempty"`
+	Value string `xml:",chardata" json:"value"`
+}
+
+// Inflection is an inflected form of a headword or compound
+type Inflection struct {
+	Form  string `xml:"Form,attr" json:"form,omitempty"` // Grammatical description of the form, if given
+	Value string `xml:",chardata" json:"value"`
+}
+
+// Lexeme is one sense of a headword
+type Lexeme struct {
+	ID           string        `xml:"ID,attr" json:"id"`
+	Definition   string        `xml:"Definition" json:"definition,omitempty"`
+	Explanation  string        `xml:"Explanation" json:"explanation,omitempty"`
+	Comment      string        `xml:"Comment" json:"comment,omitempty"`
+	GramInfo     string        `xml:"Graminfo" json:"gram_info,omitempty"`
+	Translations []Translation `xml:"Translation" json:"translations,omitempty"`
+	Examples     []Example     `xml:"Example" json:"examples,omitempty"`
+	Idioms       []Idiom       `xml:"Idiom" json:"idioms,omitempty"`
+	Compounds    []Compound    `xml:"Compound" json:"compounds,omitempty"`
+	Antonyms     []string      `xml:"Antonym" json:"antonyms,omitempty"`
+	References   []Reference   `xml:"Reference" json:"references,omitempty"`
+}
+
+// Translation is a rendering in the target language
+type Translation struct {
+	Comment string `xml:"Comment,attr" json:"comment,omitempty"`
+	Value   string `xml:",chardata" json:"value"`
+}
+
+// Example is a Swedish usage example with its translations
+type Example struct {
+	ID           string        `xml:"ID,attr" json:"id"`
+	Value        string        `xml:",chardata" json:"value"`
+	Translations []Translation `xml:"Translation" json:"translations,omitempty"`
+}
+
+// Idiom is a fixed expression with its meaning and translations
+type Idiom struct {
+	ID           string        `xml:"ID,attr" json:"id"`
+	Value        string        `xml:",chardata" json:"value"`
+	Definition   string        `xml:"Definition" json:"definition,omitempty"`
+	Translations []Translation `xml:"Translation" json:"translations,omitempty"`
+}
+
+// Compound is a compound word built on the headword
+type Compound struct {
+	ID           string        `xml:"ID,attr" json:"id"`
+	Value        string        `xml:",chardata" json:"value"`
+	Inflections  []Inflection  `xml:"Inflection" json:"inflections,omitempty"`
+	Translations []Translation `xml:"Translation" json:"translations,omitempty"`
+}
+
+// Reference points to a related headword
+type Reference struct {
+	Type  string `xml:"Type,attr" json:"type,omitempty"` // Kind of relation, e.g. "see" or "compare"
+	Value string `xml:"Value,attr" json:"value"`
+}
+
+// Decode reads a whole Lexin dictionary from r.
+// Use NewReader to process large files one article at a time instead.
+func Decode(r io.Reader) (*Dictionary, error) {
+	reader := NewReader(r)
+
+	dict := &Dictionary{XMLName: xml.Name{Local: "Dictionary"}}
+	for article, err := range reader.All() {
+		if err != nil {
+			return nil, err
+		}
+		dict.Articles = append(dict.Articles, *article)
+	}
+	dict.Attrs = reader.Attrs()
+
+	return dict, nil
+}
+
+// ParseFile reads a whole Lexin dictionary file
+func ParseFile(path string) (*Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Decode(f)
+}
+
+// Attr returns the value of a root attribute of the dictionary
+func (d *Dictionary) Attr(name string) string {
+	for _, attr := range d.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Headword returns the headword of the first lemma
+func (a *Article) Headword() string {
+	if len(a.Lemmas) == 0 {
+		return ""
+	}
+	return a.Lemmas[0].Value
+}
+
+// Translations returns the translations of every sense of every lemma
+func (a *Article) Translations() []string {
+	var translations []string
+	for _, lemma := range a.Lemmas {
+		for _, lexeme := range lemma.Lexemes {
+			translations = append(translations, lexeme.TranslationValues()...)
+		}
+	}
+	return translations
+}
+
+// RankValue returns the frequency rank as a number, if the lemma has one
+func (l *Lemma) RankValue() (int, bool) {
+	rank, err := strconv.Atoi(l.Rank)
+	if err != nil {
+		return 0, false
+	}
+	return rank, true
+}
+
+// Forms returns the inflected forms of the lemma
+func (l *Lemma) Forms() []string {
+	forms := make([]string, 0, len(l.Inflections))
+	for _, inflection := range l.Inflections {
+		if inflection.Value != "" {
+			forms = append(forms, inflection.Value)
+		}
+	}
+	return forms
+}
+
+// Pronunciation returns the first phonetic transcription
+func (l *Lemma) Pronunciation() string {
+	for _, phonetic := range l.Phonetics {
+		if phonetic.Value != "" {
+			return phonetic.Value
+		}
+	}
+	return ""
+}
+
+// TranslationValues returns the text of every translation of the sense
+func (x *Lexeme) TranslationValues() []string {
+	return translationValues(x.Translations)
+}
+
+// JoinTranslations returns the non-empty texts of translations on one line, separated by "; "
+func JoinTranslations(translations []Translation) string {
+	return strings.Join(translationValues(translations), "; ")
+}
+
+// translationValues returns the non-empty texts of translations
+func translationValues(translations []Translation) []string {
+	values := make([]string, 0, len(translations))
+	for _, t := range translations {
+		if t.Value != "" {
+			values = append(values, t.Value)
+		}
+	}
+	return values
+}
+
+// normalize trims the whitespace that surrounds text content in the XML
+func (a *Article) normalize() {
+	for i := range a.Lemmas {
+		l := &a.Lemmas[i]
+		l.Value = strings.TrimSpace(l.Value)
+		l.Comment = strings.TrimSpace(l.Comment)
+		trimPhonetics(l.Phonetics)
+		trimInflections(l.Inflections)
+
+		for j := range l.Lexemes {
+			x := &l.Lexemes[j]
+			x.Definition = strings.TrimSpace(x.Definition)
+			x.Explanation = strings.TrimSpace(x.Explanation)
+			x.Comment = strings.TrimSpace(x.Comment)
+			x.GramInfo = strings.TrimSpace(x.GramInfo)
+			trimTranslations(x.Translations)
+			for k := range x.Antonyms {
+				x.Antonyms[k] = strings.TrimSpace(x.Antonyms[k])
+			}
+			for k := range x.Examples {
+				x.Examples[k].Value = strings.TrimSpace(x.Examples[k].Value)
+				trimTranslations(x.Examples[k].Translations)
+			}
+			for k := range x.Idioms {
+				x.Idioms[k].Value = strings.TrimSpace(x.Idioms[k].Value)
+				x.Idioms[k].Definition = strings.TrimSpace(x.Idioms[k].Definition)
+				trimTranslations(x.Idioms[k].Translations)
+			}
+			for k := range x.Compounds {
+				x.Compounds[k].Value = strings.TrimSpace(x.Compounds[k].Value)
+				trimInflections(x.Compounds[k].Inflections)
+				trimTranslations(x.Compounds[k].Translations)
+			}
+		}
+	}
+}
+
+func trimPhonetics(phonetics []Phonetic) {
+	for i := range phonetics {
+		phonetics[i].Value = strings.TrimSpace(phonetics[i].Value)
+	}
+}
+
+func trimInflections(inflections []Inflection) {
+	for i := range inflections {
+		inflections[i].Value = strings.TrimSpace(inflections[i].Value)
+	}
+}
+
+func trimTranslations(translations []Translation) {
+	for i := range translations {
+		translations[i].Value = strings.TrimSpace(translations[i].Value)
+	}
+}
@@ -0,0 +1,148 @@
+package lexin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testDictionary is a small Lexin file with every kind of element
+const testDictionary = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary SourceLanguage="swe" TargetLanguage="eng">
+  <Article ID="10">
+    <Lemma ID="11" Value=" springa " Type="verb" Hyphenate="spring|a" Rank="120" Variant="" VariantID="2">
+      <Phonetic File="springa.mp3"> spr'ing:a </Phonetic>
+      <Inflection Form="pret."> sprang </Inflection>
+      <Inflection Form="sup."> sprungit </Inflection>
+      <Inflection></Inflection>
+      <Lexeme ID="12">
+        <Definition> förflytta sig snabbt </Definition>
+        <Graminfo>A springer</Graminfo>
+        <Translation Comment="fast"> run </Translation>
+        <Translation> race </Translation>
+        <Translation></Translation>
+        <Example ID="13"> springa fort <Translation>run fast</Translation></Example>
+        <Idiom ID="14"> springa benen av sig <Definition> springa mycket </Definition><Translation>run one's legs off</Translation></Idiom>
+        <Compound ID="15"> springpojke <Inflection>springpojken</Inflection><Translation>errand boy</Translation></Compound>
+        <Antonym> gå </Antonym>
+        <Reference Type="compare" Value="löpa"/>
+      </Lexeme>
+      <Lexeme ID="16">
+        <Translation>burst</Translation>
+      </Lexeme>
+      <Reference Type="see" Value="språng"/>
+      <Comment> vardagligt </Comment>
+    </Lemma>
+  </Article>
+  <Article ID="20">
+    <Lemma ID="21" Value="hus" Type="subst."/>
+  </Article>
+</Dictionary>
+`
+
+func TestDecode(t *testing.T) {
+	dict, err := Decode(strings.NewReader(testDictionary))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if got := dict.Attr("TargetLanguage"); got != "eng" {
+		t.Errorf("TargetLanguage = %q, want %q", got, "eng")
+	}
+	if got := dict.Attr("Missing"); got != "" {
+		t.Errorf("missing attribute = %q, want none", got)
+	}
+	if len(dict.Articles) != 2 {
+		t.Fatalf("got %d articles, want 2", len(dict.Articles))
+	}
+
+	// Attributes are kept as they are, text content is trimmed
+	want := Lemma{
+		ID:          "11",
+		Value:       "springa",
+		WordClass:   "verb",
+		Hyphenate:   "spring|a",
+		Rank:        "120",
+		VariantID:   "2",
+		Phonetics:   []Phonetic{{File: "springa.mp3", Value: "spr'ing:a"}},
+		Inflections: []Inflection{{Form: "pret.", Value: "sprang"}, {Form: "sup.", Value: "sprungit"}, {}},
+		Lexemes: []Lexeme{
+			{
+				ID:           "12",
+				Definition:   "förflytta sig snabbt",
+				GramInfo:     "A springer",
+				Translations: []Translation{{Comment: "fast", Value: "run"}, {Value: "race"}, {}},
+				Examples:     []Example{{ID: "13", Value: "springa fort", Translations: []Translation{{Value: "run fast"}}}},
+				Idioms:       []Idiom{{ID: "14", Value: "springa benen av sig", Definition: "springa mycket", Translations: []Translation{{Value: "run one's legs off"}}}},
+				Compounds:    []Compound{{ID: "15", Value: "springpojke", Inflections: []Inflection{{Value: "springpojken"}}, Translations: []Translation{{Value: "errand boy"}}}},
+				Antonyms:     []string{"gå"},
+				References:   []Reference{{Type: "compare", Value: "löpa"}},
+			},
+			{ID: "16", Translations: []Translation{{Value: "burst"}}},
+		},
+		References: []Reference{{Type: "see", Value: "språng"}},
+		Comment:    "vardagligt",
+	}
+	article := dict.Articles[0]
+	if article.ID != "10" || len(article.Lemmas) != 1 {
+		t.Fatalf("article = %+v, want ID 10 with one lemma", article)
+	}
+	if !reflect.DeepEqual(article.Lemmas[0], want) {
+		t.Errorf("lemma =\n%+v\nwant\n%+v", article.Lemmas[0], want)
+	}
+}
+
+func TestArticleHelpers(t *testing.T) {
+	dict, err := Decode(strings.NewReader(testDictionary))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	springa, hus := &dict.Articles[0], &dict.Articles[1]
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Headword", springa.Headword(), "springa"},
+		{"Headword without lemmas", (&Article{}).Headword(), ""},
+		{"Translations", springa.Translations(), []string{"run", "race", "burst"}},
+		{"Translations without lexemes", hus.Translations(), []string(nil)},
+		{"Forms", springa.Lemmas[0].Forms(), []string{"sprang", "sprungit"}},
+		{"Forms without inflections", hus.Lemmas[0].Forms(), []string{}},
+		{"Pronunciation", springa.Lemmas[0].Pronunciation(), "spr'ing:a"},
+		{"Pronunciation without phonetics", hus.Lemmas[0].Pronunciation(), ""},
+		{"TranslationValues", springa.Lemmas[0].Lexemes[0].TranslationValues(), []string{"run", "race"}},
+		{"JoinTranslations", JoinTranslations(springa.Lemmas[0].Lexemes[0].Translations), "run; race"},
+		{"JoinTranslations without translations", JoinTranslations(nil), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %#v, want %#v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankValue(t *testing.T) {
+	tests := []struct {
+		rank   string
+		want   int
+		wantOK bool
+	}{
+		{"120", 120, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"high", 0, false},
+	}
+
+	for _, tt := range tests {
+		lemma := Lemma{Rank: tt.rank}
+		got, ok := lemma.RankValue()
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("RankValue(%q) = %d, %v, want %d, %v", tt.rank, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
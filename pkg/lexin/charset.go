@@ -0,0 +1,44 @@
+package lexin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharsetReader converts the Latin-1 encodings found in older Lexin files to UTF-8
+func CharsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso_8859-1", "latin1", "latin-1":
+		return &latin1Reader{r: input}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", label)
+}
+
+// latin1Reader decodes ISO-8859-1 bytes into UTF-8
+type latin1Reader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	// Every Latin-1 byte becomes at most two UTF-8 bytes
+	if len(p) < 2 {
+		return 0, io.ErrShortBuffer
+	}
+	if cap(l.buf) < len(p)/2 {
+		l.buf = make([]byte, len(p)/2)
+	}
+	raw := l.buf[:len(p)/2]
+
+	n, err := l.r.Read(raw)
+	out := p[:0]
+	for _, b := range raw[:n] {
+		out = utf8.AppendRune(out, rune(b))
+	}
+
+	return len(out), err
+}
@@ -0,0 +1,99 @@
+package lexin
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+)
+
+// Reader decodes the articles of a Lexin file one at a time, so memory use
+// stays bounded by the size of a single article regardless of the file size
+type Reader struct {
+	decoder *xml.Decoder
+	attrs   []xml.Attr
+	err     error
+}
+
+// NewReader creates a streaming reader for r
+func NewReader(r io.Reader) *Reader {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = CharsetReader
+	return &Reader{decoder: decoder}
+}
+
+// Next returns the next article, or io.EOF once the document is exhausted
+func (r *Reader) Next() (*Article, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for {
+		tok, err := r.decoder.Token()
+		if err == io.EOF {
+			r.err = io.EOF
+			return nil, io.EOF
+		}
+		if err != nil {
+			r.err = fmt.Errorf("failed to parse dictionary: %v", err)
+			return nil, r.err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "Dictionary":
+			r.attrs = start.Attr
+		case "Article":
+			var article Article
+			if err := r.decoder.DecodeElement(&article, &start); err != nil {
+				r.err = fmt.Errorf("failed to parse article: %v", err)
+				return nil, r.err
+			}
+			article.normalize()
+			return &article, nil
+		}
+	}
+}
+
+// All iterates over the remaining articles, yielding a final error if decoding fails
+func (r *Reader) All() iter.Seq2[*Article, error] {
+	return func(yield func(*Article, error) bool) {
+		for {
+			article, err := r.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(article, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Attrs returns the attributes of the root element once it has been read
+func (r *Reader) Attrs() []xml.Attr {
+	return r.attrs
+}
+
+// Articles iterates over the articles of a Lexin file, closing it when done
+func Articles(path string) iter.Seq2[*Article, error] {
+	return func(yield func(*Article, error) bool) {
+		f, err := os.Open(path)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer f.Close()
+
+		for article, err := range NewReader(f).All() {
+			if !yield(article, err) {
+				return
+			}
+		}
+	}
+}
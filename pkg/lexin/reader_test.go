@@ -0,0 +1,241 @@
+package lexin
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testArticles = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary Version="1">
+  <Article ID="1"><Lemma Value="hus"/></Article>
+  <!-- comments and other elements between articles are skipped -->
+  <Info>ignored</Info>
+  <Article ID="2"><Lemma Value="bil"/></Article>
+  <Article ID="3"><Lemma Value="katt"/></Article>
+</Dictionary>
+`
+
+func TestReaderNext(t *testing.T) {
+	r := NewReader(strings.NewReader(testArticles))
+
+	for _, want := range []string{"hus", "bil", "katt"} {
+		article, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if got := article.Headword(); got != want {
+			t.Errorf("headword = %q, want %q", got, want)
+		}
+	}
+	if got := r.Attrs(); len(got) != 1 || got[0].Value != "1" {
+		t.Errorf("Attrs = %v, want Version=1", got)
+	}
+
+	// EOF is sticky
+	for range 2 {
+		if article, err := r.Next(); err != io.EOF || article != nil {
+			t.Errorf("Next at the end = %v, %v, want nil, EOF", article, err)
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int // Articles read before the error
+		wantErr string
+	}{
+		{
+			name:    "truncated document",
+			input:   `<Dictionary><Article ID="1"><Lemma Value="hus"/></Article><Article ID="2"><Lemma`,
+			want:    1,
+			wantErr: "failed to parse",
+		},
+		{
+			name:    "mismatched tags in an article",
+			input:   `<Dictionary><Article ID="1"><Lemma Value="hus"></Article></Dictionary>`,
+			wantErr: "failed to parse article",
+		},
+		{
+			name:    "unsupported charset",
+			input:   `<?xml version="1.0" encoding="EBCDIC"?><Dictionary/>`,
+			wantErr: "unsupported charset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input))
+			for range tt.want {
+				if _, err := r.Next(); err != nil {
+					t.Fatalf("Next: %v", err)
+				}
+			}
+
+			_, err := r.Next()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Next = %v, want an error containing %q", err, tt.wantErr)
+			}
+
+			// The error is returned again instead of decoding past it
+			if _, again := r.Next(); again != err {
+				t.Errorf("second Next = %v, want %v", again, err)
+			}
+
+			// All yields the error once and stops
+			var errs int
+			for article, err := range NewReader(strings.NewReader(tt.input)).All() {
+				if err != nil {
+					errs++
+				} else if article == nil {
+					t.Error("All yielded a nil article without an error")
+				}
+			}
+			if errs != 1 {
+				t.Errorf("All yielded %d errors, want 1", errs)
+			}
+		})
+	}
+}
+
+func TestReaderAllStopsEarly(t *testing.T) {
+	r := NewReader(strings.NewReader(testArticles))
+	for article, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if article.ID != "1" {
+			t.Errorf("first article = %s, want 1", article.ID)
+		}
+		break
+	}
+
+	// Breaking out of the loop leaves the remaining articles to read
+	var ids []string
+	for article, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, article.ID)
+	}
+	if strings.Join(ids, ",") != "2,3" {
+		t.Errorf("remaining articles = %v, want [2 3]", ids)
+	}
+}
+
+func TestArticles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swe_eng.xml")
+	if err := os.WriteFile(path, []byte(testArticles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for article, err := range Articles(path) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, article.ID)
+		if len(ids) == 2 {
+			break
+		}
+	}
+	if strings.Join(ids, ",") != "1,2" {
+		t.Errorf("articles = %v, want [1 2]", ids)
+	}
+
+	// A missing file is a single error
+	var errs []error
+	for _, err := range Articles(filepath.Join(t.TempDir(), "missing.xml")) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrNotExist) {
+		t.Errorf("missing file yielded %v, want one not-exist error", errs)
+	}
+}
+
+func TestReaderLatin1(t *testing.T) {
+	input := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<Dictionary><Article ID=\"1\"><Lemma Value=\"\xe5ka\"><Lexeme><Translation>\xe4r \xf6verens</Translation></Lexeme></Lemma></Article></Dictionary>"
+
+	article, err := NewReader(strings.NewReader(input)).Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if got := article.Headword(); got != "åka" {
+		t.Errorf("headword = %q, want %q", got, "åka")
+	}
+	if got := article.Translations(); len(got) != 1 || got[0] != "är överens" {
+		t.Errorf("translations = %q, want [är överens]", got)
+	}
+}
+
+func TestCharsetReader(t *testing.T) {
+	tests := []struct {
+		label   string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{label: "UTF-8", input: "åka", want: "åka"},
+		{label: "us-ascii", input: "bil", want: "bil"},
+		{label: "ISO-8859-1", input: "\xe5ka", want: "åka"},
+		{label: "latin1", input: "\xc5\xc4\xd6\xff", want: "ÅÄÖÿ"},
+		{label: "windows-1252", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			r, err := CharsetReader(tt.label, strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("CharsetReader(%q) accepted an unsupported charset", tt.label)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CharsetReader(%q): %v", tt.label, err)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatin1ReaderSmallBuffers(t *testing.T) {
+	r, err := CharsetReader("latin1", strings.NewReader("\xe5\xe4\xf6"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Read(make([]byte, 1)); err != io.ErrShortBuffer {
+		t.Errorf("Read into one byte = %v, want %v", err, io.ErrShortBuffer)
+	}
+
+	// Two bytes hold exactly one converted character
+	var out []byte
+	buf := make([]byte, 2)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out) != "åäö" {
+		t.Errorf("read %q, want %q", out, "åäö")
+	}
+}
@@ -0,0 +1,203 @@
+package lexin
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ReverseEntry links a target-language term back to the Swedish sense it translates
+type ReverseEntry struct {
+	Key     string // NormalizeTerm of Term, used for matching
+	Term    string // The term as written in the translation
+	Article *Article
+	Lemma   *Lemma
+	Lexeme  *Lexeme
+}
+
+// ReverseIndex maps target-language terms to the Swedish entries they translate
+type ReverseIndex struct {
+	terms map[string][]ReverseEntry
+}
+
+// NewReverseIndex creates an empty reverse index
+func NewReverseIndex() *ReverseIndex {
+	return &ReverseIndex{terms: make(map[string][]ReverseEntry)}
+}
+
+// BuildReverseIndex reads a Lexin file and indexes all of its translations
+func BuildReverseIndex(path string) (*ReverseIndex, error) {
+	idx := NewReverseIndex()
+	for article, err := range Articles(path) {
+		if err != nil {
+			return nil, err
+		}
+		idx.Add(article)
+	}
+	return idx, nil
+}
+
+// Add indexes the translations of an article
+func (idx *ReverseIndex) Add(article *Article) {
+	for _, entry := range article.ReverseEntries() {
+		idx.terms[entry.Key] = append(idx.terms[entry.Key], entry)
+	}
+}
+
+// Lookup returns the Swedish senses translated by term, after normalization
+func (idx *ReverseIndex) Lookup(term string) []ReverseEntry {
+	return idx.terms[NormalizeTerm(term)]
+}
+
+// Terms returns the normalized terms in the index in sorted order
+func (idx *ReverseIndex) Terms() []string {
+	terms := make([]string, 0, len(idx.terms))
+	for term := range idx.terms {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// Len returns the number of distinct normalized terms
+func (idx *ReverseIndex) Len() int {
+	return len(idx.terms)
+}
+
+// ReverseEntries splits every translation of the article into single terms,
+// one entry per distinct term and sense
+func (a *Article) ReverseEntries() []ReverseEntry {
+	var entries []ReverseEntry
+	for i := range a.Lemmas {
+		lemma := &a.Lemmas[i]
+		for j := range lemma.Lexemes {
+			lexeme := &lemma.Lexemes[j]
+			seen := make(map[string]bool)
+			for _, translation := range lexeme.TranslationValues() {
+				for _, term := range SplitTranslation(translation) {
+					key := NormalizeTerm(term)
+					if key == "" || seen[key] {
+						continue
+					}
+					seen[key] = true
+					entries = append(entries, ReverseEntry{Key: key, Term: term, Article: a, Lemma: lemma, Lexeme: lexeme})
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// SplitTranslation splits a translation that lists several renderings, such as
+// "burst, crack" or "رَكَضَ؛ جرى", on commas and semicolons in Latin, Arabic and
+// Ethiopic script. Separators inside parentheses are left alone.
+func SplitTranslation(translation string) []string {
+	var terms []string
+	depth := 0
+	start := 0
+	for i, r := range translation {
+		switch r {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+		case ',', ';', '،', '؛', '፣', '፤':
+			if depth == 0 {
+				if term := strings.TrimSpace(translation[start:i]); term != "" {
+					terms = append(terms, term)
+				}
+				start = i + len(string(r))
+			}
+		}
+	}
+	if term := strings.TrimSpace(translation[start:]); term != "" {
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// NormalizeTerm folds a term for matching: it lower-cases, drops parenthesized
+// comments, collapses whitespace, strips Arabic vowel marks, tatweel and
+// invisible formatting characters, and unifies script variants (Arabic and
+// Persian alef, yeh and kaf forms, Arabic-Indic digits, Greek accents and final
+// sigma, Cyrillic ё).
+func NormalizeTerm(term string) string {
+	if stripped := stripParenthesized(term); strings.TrimSpace(stripped) != "" {
+		term = stripped
+	}
+
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(term) {
+		if ignorable(r) {
+			continue
+		}
+		if unicode.IsSpace(r) {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(foldVariant(r))
+	}
+	return b.String()
+}
+
+// stripParenthesized removes text in parentheses or brackets, e.g. "(to) run"
+func stripParenthesized(s string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case (r == ')' || r == ']') && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ignorable reports whether a rune carries no meaning for matching
+func ignorable(r rune) bool {
+	switch {
+	case r == 0x0640: // Arabic tatweel
+		return true
+	case r >= 0x0610 && r <= 0x061A, r >= 0x064B && r <= 0x065F, r == 0x0670, r >= 0x06D6 && r <= 0x06ED:
+		return true // Arabic vowel marks and Quranic annotations
+	case r >= 0x200B && r <= 0x200F, r >= 0x202A && r <= 0x202E, r >= 0x2066 && r <= 0x2069, r == 0xFEFF, r == 0x00AD:
+		return true // Zero-width characters, direction marks, soft hyphen
+	}
+	return false
+}
+
+// scriptVariants maps letters to the form used for matching
+var scriptVariants = map[rune]rune{
+	'أ': 'ا', 'إ': 'ا', 'آ': 'ا', 'ٱ': 'ا',
+	'ى': 'ي', 'ی': 'ي',
+	'ک': 'ك',
+	'ة': 'ه', 'ۀ': 'ه',
+	'ά': 'α', 'έ': 'ε', 'ή': 'η', 'ί': 'ι', 'ΐ': 'ι', 'ϊ': 'ι',
+	'ό': 'ο', 'ύ': 'υ', 'ΰ': 'υ', 'ϋ': 'υ', 'ώ': 'ω', 'ς': 'σ',
+	'ё': 'е',
+}
+
+// foldVariant unifies script variants and converts Arabic-Indic digits to ASCII
+func foldVariant(r rune) rune {
+	switch {
+	case r >= '٠' && r <= '٩':
+		return '0' + (r - '٠')
+	case r >= '۰' && r <= '۹':
+		return '0' + (r - '۰')
+	}
+	if v, ok := scriptVariants[r]; ok {
+		return v
+	}
+	return r
+}
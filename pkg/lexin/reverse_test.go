@@ -0,0 +1,131 @@
+package lexin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitTranslation(t *testing.T) {
+	tests := []struct {
+		translation string
+		want        []string
+	}{
+		{"run", []string{"run"}},
+		{"burst, crack", []string{"burst", "crack"}},
+		{"run; race ,  dash", []string{"run", "race", "dash"}},
+		{"رَكَضَ؛ جرى", []string{"رَكَضَ", "جرى"}},   // Arabic semicolon
+		{"بيت، منزل", []string{"بيت", "منزل"}},       // Arabic comma
+		{"ሮጠ፣ ጋለበ፤ ሸሸ", []string{"ሮጠ", "ጋለበ", "ሸሸ"}}, // Ethiopic comma and semicolon
+		{"house (building, home), hut", []string{"house (building, home)", "hut"}},
+		{"car [colloquial; rare]", []string{"car [colloquial; rare]"}},
+		{", run,, ;", []string{"run"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := SplitTranslation(tt.translation); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitTranslation(%q) = %q, want %q", tt.translation, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTerm(t *testing.T) {
+	tests := []struct {
+		name string
+		term string
+		want string
+	}{
+		{"case and whitespace", "  To   Run\t", "to run"},
+		{"parenthesized comment", "(to) run [fast]", "run"},
+		{"only a comment", "(hus)", "(hus)"},
+		{"Arabic vowel marks", "رَكَضَ", "ركض"},
+		{"superscript alef", "هٰذا", "هذا"},
+		{"tatweel", "بـيـت", "بيت"},
+		{"alef variants", "أإآٱ", "اااا"},
+		{"alef maqsura and Persian yeh", "جرى کتابی", "جري كتابي"},
+		{"teh marbuta and heh with yeh", "فكاهة خانۀ", "فكاهه خانه"},
+		{"Arabic-Indic and Persian digits", "٣ ۴", "3 4"},
+		{"direction marks and isolates", "\u200fبيت\u2067\u2069", "بيت"},
+		{"Greek accents and final sigma", "Λέξις ώρα", "λεξισ ωρα"},
+		{"Cyrillic yo", "Ёлка", "елка"},
+		{"empty", "   ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeTerm(tt.term); got != tt.want {
+				t.Errorf("NormalizeTerm(%q) = %q, want %q", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+const testReverseXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Dictionary>
+  <Article ID="1">
+    <Lemma ID="1" Value="springa">
+      <Lexeme ID="1"><Translation>جرى؛ رَكَضَ</Translation><Translation>جرى</Translation></Lexeme>
+      <Lexeme ID="2"><Translation>انفجر</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="2">
+    <Lemma ID="2" Value="löpa">
+      <Lexeme ID="3"><Translation>جَرَى (بسرعة)</Translation></Lexeme>
+    </Lemma>
+  </Article>
+  <Article ID="3">
+    <Lemma ID="3" Value="och">
+      <Lexeme ID="4"/>
+    </Lemma>
+  </Article>
+</Dictionary>
+`
+
+func TestReverseIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swe_ara.xml")
+	if err := os.WriteFile(path, []byte(testReverseXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := BuildReverseIndex(path)
+	if err != nil {
+		t.Fatalf("BuildReverseIndex: %v", err)
+	}
+
+	if got := strings.Join(idx.Terms(), ","); got != "انفجر,جري,ركض" {
+		t.Errorf("Terms = %s, want انفجر,جري,ركض", got)
+	}
+	if idx.Len() != 3 {
+		t.Errorf("Len = %d, want 3", idx.Len())
+	}
+
+	// A spelling variant finds every sense, once per sense, with the term as written
+	entries := idx.Lookup("جري")
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Lemma.Value+"/"+entry.Lexeme.ID+"/"+entry.Term)
+		if entry.Key != "جري" {
+			t.Errorf("entry key = %q, want %q", entry.Key, "جري")
+		}
+	}
+	if want := []string{"springa/1/جرى", "löpa/3/جَرَى (بسرعة)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lookup = %q, want %q", got, want)
+	}
+	if entries[0].Article.ID != "1" || entries[1].Article.ID != "2" {
+		t.Errorf("entries point at articles %s and %s, want 1 and 2", entries[0].Article.ID, entries[1].Article.ID)
+	}
+
+	if got := idx.Lookup("رَكَضَ"); len(got) != 1 || got[0].Lexeme.ID != "1" {
+		t.Errorf("Lookup with vowel marks = %+v, want sense 1", got)
+	}
+	if got := idx.Lookup("بيت"); len(got) != 0 {
+		t.Errorf("Lookup of a missing term = %+v, want nothing", got)
+	}
+
+	if _, err := BuildReverseIndex(filepath.Join(t.TempDir(), "missing.xml")); err == nil {
+		t.Error("BuildReverseIndex succeeded for a missing file")
+	}
+}